@@ -0,0 +1,52 @@
+package hls
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseAttributes parses an HLS attribute list: comma-separated KEY=VALUE pairs where values
+// are either quoted strings (which may contain commas) or plain tokens
+func ParseAttributes(list string) (map[string]string, error) {
+	attrs := make(map[string]string)
+
+	for rest := strings.TrimSpace(list); rest != ""; {
+		key, after, ok := strings.Cut(rest, "=")
+		if !ok {
+			return nil, fmt.Errorf("attribute without value: %q", rest)
+		}
+
+		key = strings.TrimSpace(key)
+
+		var value string
+
+		if strings.HasPrefix(after, `"`) {
+			end := strings.IndexByte(after[1:], '"')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated quoted value of %s", key)
+			}
+
+			value = after[1 : end+1]
+			after = after[end+2:]
+		} else {
+			end := strings.IndexByte(after, ',')
+			if end < 0 {
+				end = len(after)
+			}
+
+			value = after[:end]
+			after = after[end:]
+		}
+
+		attrs[key] = value
+
+		after = strings.TrimSpace(after)
+		if after != "" && !strings.HasPrefix(after, ",") {
+			return nil, fmt.Errorf("unexpected characters after %s: %q", key, after)
+		}
+
+		rest = strings.TrimSpace(strings.TrimPrefix(after, ","))
+	}
+
+	return attrs, nil
+}
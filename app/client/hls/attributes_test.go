@@ -0,0 +1,20 @@
+package hls
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAttributes(t *testing.T) {
+	attrs, err := ParseAttributes(`BANDWIDTH=160000,CODECS="avc1.4D401F,mp4a.40.2",VIDEO="audio_only",FRAME-RATE=30.000`)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]string{
+		"BANDWIDTH":  "160000",
+		"CODECS":     "avc1.4D401F,mp4a.40.2",
+		"VIDEO":      "audio_only",
+		"FRAME-RATE": "30.000",
+	}, attrs)
+}
@@ -0,0 +1,215 @@
+package hls
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"nicemaxxingbot/app/util/telemetry"
+
+	"github.com/samber/do"
+	"go.opentelemetry.io/otel/attribute"
+	otelmetric "go.opentelemetry.io/otel/metric"
+)
+
+const (
+	// consecutive playlist failures after which the stream is considered gone
+	maxPlaylistFailures = 3
+	// a playlist without new segments for this long is considered stale
+	minStaleTimeout = 30 * time.Second
+	segmentRetries  = 2
+)
+
+// SegmentError is a failure to download a single segment
+type SegmentError struct {
+	Sequence int64
+	URI      string
+	Err      error
+}
+
+func (e *SegmentError) Error() string {
+	return fmt.Sprintf("segment %d: %v", e.Sequence, e.Err)
+}
+
+func (e *SegmentError) Unwrap() error {
+	return e.Err
+}
+
+// Handler receives downloaded segments in sequence order. Segments after a gap in the sequence
+// (missed or failed segments) are marked as discontinuities.
+type Handler func(seg Segment, data []byte) error
+
+// Client follows live HLS media playlists and downloads their segments
+type Client struct {
+	client  *http.Client
+	metrics *telemetry.Metrics
+}
+
+func NewClient(di *do.Injector) (*Client, error) {
+	return &Client{
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		metrics: do.MustInvoke[*telemetry.Metrics](di),
+	}, nil
+}
+
+// Follow polls the media playlist and passes every new segment to onSegment until the playlist ends,
+// stops updating or fails repeatedly, or until onSegment returns an error
+func (c *Client) Follow(ctx context.Context, playlistURL string, onSegment Handler) error {
+	base, err := url.Parse(playlistURL)
+	if err != nil {
+		return fmt.Errorf("invalid playlist URL: %w", err)
+	}
+
+	logger := slog.With(slog.String("playlist", base.Path))
+
+	last := int64(-1)
+	gap := false
+	failures := 0
+	lastNewSegment := time.Now()
+
+	for {
+		playlist, err := c.fetchPlaylist(ctx, base)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			failures++
+			if failures >= maxPlaylistFailures {
+				return fmt.Errorf("fetchPlaylist: %w", err)
+			}
+
+			logger.Warn("Failed to fetch playlist",
+				slog.Int("failures", failures),
+				slog.Any("error", err),
+			)
+		} else {
+			failures = 0
+		}
+
+		for _, seg := range playlist.Segments {
+			if seg.Sequence <= last {
+				continue
+			}
+
+			if last >= 0 && seg.Sequence > last+1 {
+				missed := seg.Sequence - last - 1
+				c.metrics.HLSSegments.Add(ctx, missed, otelmetric.WithAttributes(attribute.String("status", "missed")))
+				logger.Warn("Segments dropped out of the playlist before they were downloaded",
+					slog.Int64("from", last+1),
+					slog.Int64("count", missed),
+				)
+				gap = true
+			}
+
+			last = seg.Sequence
+			lastNewSegment = time.Now()
+
+			data, err := c.fetchSegment(ctx, seg)
+			if err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+
+				c.metrics.HLSSegments.Add(ctx, 1, otelmetric.WithAttributes(attribute.String("status", "failed")))
+				logger.Error("Failed to download segment",
+					slog.Any("error", err),
+				)
+				gap = true
+				continue
+			}
+
+			c.metrics.HLSSegments.Add(ctx, 1, otelmetric.WithAttributes(attribute.String("status", "ok")))
+
+			if gap {
+				seg.Discontinuity = true
+				gap = false
+			}
+
+			if err = onSegment(seg, data); err != nil {
+				return err
+			}
+		}
+
+		if playlist.EndList {
+			return nil
+		}
+
+		staleTimeout := max(3*playlist.TargetDuration, minStaleTimeout)
+		if time.Since(lastNewSegment) > staleTimeout {
+			return fmt.Errorf("no new segments for %v", staleTimeout)
+		}
+
+		// new segments appear every target duration, polling twice as often keeps the latency low
+		pollInterval := max(playlist.TargetDuration/2, time.Second)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+func (c *Client) fetchPlaylist(ctx context.Context, playlistURL *url.URL) (MediaPlaylist, error) {
+	body, err := c.get(ctx, playlistURL.String())
+	if err != nil {
+		return MediaPlaylist{}, err
+	}
+
+	playlist, err := ParseMediaPlaylist(string(body), playlistURL)
+	if err != nil {
+		return MediaPlaylist{}, fmt.Errorf("ParseMediaPlaylist: %w", err)
+	}
+
+	return playlist, nil
+}
+
+func (c *Client) fetchSegment(ctx context.Context, seg Segment) ([]byte, error) {
+	var err error
+
+	for range segmentRetries {
+		var data []byte
+
+		if data, err = c.get(ctx, seg.URI); err == nil {
+			return data, nil
+		}
+	}
+
+	return nil, &SegmentError{
+		Sequence: seg.Sequence,
+		URI:      seg.URI,
+		Err:      err,
+	}
+}
+
+func (c *Client) get(ctx context.Context, uri string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", uri, nil)
+	if err != nil {
+		return nil, fmt.Errorf("NewRequestWithContext: %w", err)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Do: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("ReadAll: %w", err)
+	}
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	return body, nil
+}
@@ -0,0 +1,63 @@
+package hls
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"nicemaxxingbot/app/util/telemetry"
+
+	"github.com/samber/do"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFollow(t *testing.T) {
+	var polls atomic.Int32
+
+	playlists := []string{
+		"#EXTM3U\n#EXT-X-TARGETDURATION:1\n#EXT-X-MEDIA-SEQUENCE:10\n#EXTINF:1.0,live\n10.ts\n#EXTINF:1.0,live\n11.ts\n",
+		// 12 dropped out of the window, 14 can't be downloaded
+		"#EXTM3U\n#EXT-X-TARGETDURATION:1\n#EXT-X-MEDIA-SEQUENCE:13\n#EXTINF:1.0,live\n13.ts\n#EXTINF:1.0,live\n14.ts\n#EXTINF:1.0,live\n15.ts\n#EXT-X-ENDLIST\n",
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/playlist.m3u8":
+			poll := min(int(polls.Add(1))-1, len(playlists)-1)
+			_, _ = fmt.Fprint(w, playlists[poll])
+		case "/14.ts":
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			_, _ = fmt.Fprint(w, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	di := do.New()
+	do.ProvideValue(di, telemetry.NewNoopMetrics())
+
+	client, err := NewClient(di)
+	require.NoError(t, err)
+
+	var sequences []int64
+	var discontinuities []int64
+
+	err = client.Follow(context.Background(), server.URL+"/playlist.m3u8", func(seg Segment, data []byte) error {
+		assert.Equal(t, fmt.Sprintf("/%d.ts", seg.Sequence), string(data))
+
+		sequences = append(sequences, seg.Sequence)
+		if seg.Discontinuity {
+			discontinuities = append(discontinuities, seg.Sequence)
+		}
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []int64{10, 11, 13, 15}, sequences)
+	assert.Equal(t, []int64{13, 15}, discontinuities)
+}
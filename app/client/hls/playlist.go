@@ -0,0 +1,232 @@
+package hls
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Segment is a media segment of an HLS media playlist
+type Segment struct {
+	// Sequence is the media sequence number of the segment
+	Sequence int64
+	// Duration from #EXTINF
+	Duration time.Duration
+	// Title from #EXTINF, Twitch uses it to mark ads (e.g. "Amazon|...") and live content ("live")
+	Title string
+	// URI of the segment, resolved against the playlist URL
+	URI string
+	// Discontinuity is set if the segment follows #EXT-X-DISCONTINUITY, timestamps and encoding may change
+	Discontinuity bool
+	// ProgramDateTime is the wall clock time of the first sample, zero if the playlist doesn't tell
+	ProgramDateTime time.Time
+	// Ad is set for segments of ad breaks stitched into the stream by Twitch
+	Ad bool
+}
+
+// DateRange is an #EXT-X-DATERANGE tag, Twitch uses them to announce ad breaks
+type DateRange struct {
+	ID        string
+	Class     string
+	StartDate time.Time
+	// Duration is zero if unknown
+	Duration time.Duration
+}
+
+// Ad reports whether the range is a Twitch ad break
+func (r DateRange) Ad() bool {
+	return strings.HasPrefix(r.ID, "stitched-ad") ||
+		strings.HasPrefix(r.Class, "twitch-stitched-ad") ||
+		strings.HasPrefix(r.Class, "twitch-maf-ad") ||
+		r.Class == "twitch-ads"
+}
+
+// Contains reports whether t falls into the range
+func (r DateRange) Contains(t time.Time) bool {
+	return !t.Before(r.StartDate) && t.Before(r.StartDate.Add(r.Duration))
+}
+
+// MediaPlaylist is a parsed HLS media playlist
+type MediaPlaylist struct {
+	TargetDuration time.Duration
+	MediaSequence  int64
+	Segments       []Segment
+	DateRanges     []DateRange
+	// EndList is set once the stream is over
+	EndList bool
+}
+
+// ParseMediaPlaylist parses an HLS media playlist, segment URIs are resolved against base
+func ParseMediaPlaylist(data string, base *url.URL) (MediaPlaylist, error) {
+	var result MediaPlaylist
+	var pending Segment
+	var hasInfo bool
+
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	lineNumber := 0
+	sequence := int64(0)
+
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+
+		if lineNumber == 1 {
+			if line != "#EXTM3U" {
+				return MediaPlaylist{}, fmt.Errorf("not an m3u8 playlist: %q", line)
+			}
+			continue
+		}
+
+		if line == "" {
+			continue
+		}
+
+		tag, value, _ := strings.Cut(line, ":")
+
+		switch {
+		case tag == "#EXT-X-TARGETDURATION":
+			seconds, err := strconv.Atoi(value)
+			if err != nil {
+				return MediaPlaylist{}, fmt.Errorf("line %d: invalid target duration %q: %w", lineNumber, value, err)
+			}
+			result.TargetDuration = time.Duration(seconds) * time.Second
+
+		case tag == "#EXT-X-MEDIA-SEQUENCE":
+			number, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return MediaPlaylist{}, fmt.Errorf("line %d: invalid media sequence %q: %w", lineNumber, value, err)
+			}
+			result.MediaSequence = number
+			sequence = number
+
+		case tag == "#EXTINF":
+			durationValue, title, _ := strings.Cut(value, ",")
+
+			seconds, err := strconv.ParseFloat(durationValue, 64)
+			if err != nil {
+				return MediaPlaylist{}, fmt.Errorf("line %d: invalid segment duration %q: %w", lineNumber, durationValue, err)
+			}
+
+			pending.Duration = time.Duration(math.Round(seconds*1000)) * time.Millisecond
+			pending.Title = title
+			hasInfo = true
+
+		case tag == "#EXT-X-DISCONTINUITY":
+			pending.Discontinuity = true
+
+		case tag == "#EXT-X-PROGRAM-DATE-TIME":
+			programDateTime, err := time.Parse(time.RFC3339Nano, value)
+			if err != nil {
+				return MediaPlaylist{}, fmt.Errorf("line %d: invalid program date time %q: %w", lineNumber, value, err)
+			}
+			pending.ProgramDateTime = programDateTime
+
+		case tag == "#EXT-X-DATERANGE":
+			dateRange, err := parseDateRange(value)
+			if err != nil {
+				return MediaPlaylist{}, fmt.Errorf("line %d: %w", lineNumber, err)
+			}
+			result.DateRanges = append(result.DateRanges, dateRange)
+
+		case tag == "#EXT-X-ENDLIST":
+			result.EndList = true
+
+		case strings.HasPrefix(line, "#"):
+			// other tags (#EXT-X-TWITCH-PREFETCH, #EXT-X-VERSION, ...) and comments
+
+		default:
+			if !hasInfo {
+				return MediaPlaylist{}, fmt.Errorf("line %d: segment without #EXTINF", lineNumber)
+			}
+
+			ref, err := url.Parse(line)
+			if err != nil {
+				return MediaPlaylist{}, fmt.Errorf("line %d: invalid segment URI: %w", lineNumber, err)
+			}
+			if base != nil {
+				ref = base.ResolveReference(ref)
+			}
+
+			pending.URI = ref.String()
+			pending.Sequence = sequence
+			result.Segments = append(result.Segments, pending)
+
+			sequence++
+			pending = Segment{}
+			hasInfo = false
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return MediaPlaylist{}, fmt.Errorf("scan: %w", err)
+	}
+
+	if lineNumber == 0 {
+		return MediaPlaylist{}, fmt.Errorf("empty playlist")
+	}
+
+	for i := range result.Segments {
+		result.Segments[i].Ad = result.isAd(result.Segments[i])
+	}
+
+	return result, nil
+}
+
+// isAd detects stitched ads by the segment title (e.g. "Amazon|123456") or by an ad date range covering the segment
+func (p MediaPlaylist) isAd(seg Segment) bool {
+	if strings.Contains(seg.Title, "Amazon") {
+		return true
+	}
+
+	if seg.ProgramDateTime.IsZero() {
+		return false
+	}
+
+	for _, dateRange := range p.DateRanges {
+		if dateRange.Ad() && dateRange.Contains(seg.ProgramDateTime) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func parseDateRange(list string) (DateRange, error) {
+	attrs, err := ParseAttributes(list)
+	if err != nil {
+		return DateRange{}, err
+	}
+
+	dateRange := DateRange{
+		ID:    attrs["ID"],
+		Class: attrs["CLASS"],
+	}
+
+	if value, ok := attrs["START-DATE"]; ok {
+		if dateRange.StartDate, err = time.Parse(time.RFC3339Nano, value); err != nil {
+			return DateRange{}, fmt.Errorf("invalid START-DATE %q: %w", value, err)
+		}
+	}
+
+	// Twitch announces ad breaks with the planned duration
+	for _, key := range []string{"DURATION", "PLANNED-DURATION"} {
+		value, ok := attrs[key]
+		if !ok {
+			continue
+		}
+
+		seconds, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return DateRange{}, fmt.Errorf("invalid %s %q: %w", key, value, err)
+		}
+
+		dateRange.Duration = time.Duration(math.Round(seconds*1000)) * time.Millisecond
+		break
+	}
+
+	return dateRange, nil
+}
@@ -0,0 +1,95 @@
+package hls
+
+import (
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMediaPlaylist(t *testing.T) {
+	data, err := os.ReadFile("testdata/live.m3u8")
+	require.NoError(t, err)
+
+	base, err := url.Parse("https://video-weaver.fra05.hls.ttvnw.net/v1/playlist/audio_only.m3u8")
+	require.NoError(t, err)
+
+	playlist, err := ParseMediaPlaylist(string(data), base)
+	require.NoError(t, err)
+
+	assert.Equal(t, 6*time.Second, playlist.TargetDuration)
+	assert.Equal(t, int64(2515), playlist.MediaSequence)
+	assert.False(t, playlist.EndList)
+
+	assert.Equal(t, []Segment{
+		{
+			Sequence:        2515,
+			Duration:        2 * time.Second,
+			Title:           "live",
+			URI:             "https://video-edge-c2a5d4.fra05.abs.hls.ttvnw.net/v1/segment/seg-2515.ts",
+			ProgramDateTime: time.Date(2025, 9, 1, 13, 23, 45, 0, time.UTC),
+		},
+		{
+			Sequence:        2516,
+			Duration:        2 * time.Second,
+			Title:           "live",
+			URI:             "https://video-weaver.fra05.hls.ttvnw.net/v1/playlist/seg-2516.ts",
+			ProgramDateTime: time.Date(2025, 9, 1, 13, 23, 47, 0, time.UTC),
+		},
+		{
+			Sequence:        2517,
+			Duration:        2002 * time.Millisecond,
+			Title:           "live",
+			URI:             "https://video-weaver.fra05.hls.ttvnw.net/v1/segment/seg-2517.ts?token=abc",
+			Discontinuity:   true,
+			ProgramDateTime: time.Date(2025, 9, 1, 13, 23, 49, 0, time.UTC),
+		},
+	}, playlist.Segments)
+}
+
+func TestParseMediaPlaylistEndList(t *testing.T) {
+	playlist, err := ParseMediaPlaylist("#EXTM3U\n#EXT-X-TARGETDURATION:2\n#EXTINF:1.5,\na.ts\n#EXT-X-ENDLIST\n", nil)
+	require.NoError(t, err)
+
+	assert.True(t, playlist.EndList)
+	assert.Equal(t, int64(0), playlist.Segments[0].Sequence)
+	assert.Equal(t, "a.ts", playlist.Segments[0].URI)
+}
+
+func TestParseMediaPlaylistErrors(t *testing.T) {
+	for _, playlist := range []string{
+		"",
+		"#EXTM3U\nseg.ts\n",
+		"#EXTM3U\n#EXTINF:abc,live\nseg.ts\n",
+		"#EXTM3U\n#EXT-X-MEDIA-SEQUENCE:x\n",
+	} {
+		_, err := ParseMediaPlaylist(playlist, nil)
+		assert.Error(t, err, playlist)
+	}
+}
+
+func TestParseMediaPlaylistAds(t *testing.T) {
+	data, err := os.ReadFile("testdata/ad.m3u8")
+	require.NoError(t, err)
+
+	playlist, err := ParseMediaPlaylist(string(data), nil)
+	require.NoError(t, err)
+
+	require.Len(t, playlist.DateRanges, 2)
+	assert.True(t, playlist.DateRanges[0].Ad())
+	assert.Equal(t, 4*time.Second, playlist.DateRanges[0].Duration)
+	assert.False(t, playlist.DateRanges[1].Ad())
+
+	var ads []bool
+	var discontinuities []bool
+	for _, seg := range playlist.Segments {
+		ads = append(ads, seg.Ad)
+		discontinuities = append(discontinuities, seg.Discontinuity)
+	}
+
+	assert.Equal(t, []bool{false, true, true, true, false}, ads)
+	assert.Equal(t, []bool{false, true, false, true, true}, discontinuities)
+}
@@ -3,24 +3,63 @@ package openai
 import (
 	"context"
 	_ "embed"
+	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"nicemaxxingbot/app/config"
+	"nicemaxxingbot/app/util/prompt"
+	"nicemaxxingbot/app/util/telemetry"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/rofleksey/meg"
 	"github.com/samber/do"
 	"github.com/sashabaranov/go-openai"
+	"go.opentelemetry.io/otel/attribute"
+	otelmetric "go.opentelemetry.io/otel/metric"
 )
 
+// SystemPrompt is the built-in classifier prompt template
+//
 //go:embed SYSTEM_PROMPT.txt
-var systemPrompt string
+var SystemPrompt string
+
+//go:embed JSON_FORMAT_PROMPT.txt
+var jsonFormatPrompt string
+
+// structuredRetryInterval is how long a model that rejected structured output gets the text protocol
+// before structured output is tried again
+const structuredRetryInterval = time.Hour
 
 type Client struct {
 	cfg        *config.Config
+	metrics    *telemetry.Metrics
 	freeClient *openai.Client
 	client     *openai.Client
+	// built-in prompt rendered without variables
+	defaultPrompt string
+
+	freeProtocol protocol
+	protocol     protocol
+}
+
+// protocol tracks whether the model is sent the text protocol instead of structured output
+type protocol struct {
+	// configured to always use the text protocol
+	text bool
+	// unix nanoseconds until which the text protocol is used after the model rejected structured output
+	textUntil atomic.Int64
+}
+
+func (p *protocol) useText(now time.Time) bool {
+	return p.text || now.UnixNano() < p.textUntil.Load()
+}
+
+// fallBack switches to the text protocol for structuredRetryInterval
+func (p *protocol) fallBack(now time.Time) {
+	p.textUntil.Store(now.Add(structuredRetryInterval).UnixNano())
 }
 
 func newOpenaiClient(cfg *config.Config, free bool) *openai.Client {
@@ -43,14 +82,30 @@ func newOpenaiClient(cfg *config.Config, free bool) *openai.Client {
 func NewClient(di *do.Injector) (*Client, error) {
 	cfg := do.MustInvoke[*config.Config](di)
 
-	return &Client{
-		cfg:        cfg,
-		freeClient: newOpenaiClient(cfg, true),
-		client:     newOpenaiClient(cfg, false),
-	}, nil
+	tmpl, err := prompt.Parse("classifier", SystemPrompt)
+	if err != nil {
+		return nil, fmt.Errorf("prompt.Parse: %w", err)
+	}
+
+	defaultPrompt, err := tmpl.Render(prompt.Vars{})
+	if err != nil {
+		return nil, fmt.Errorf("Render: %w", err)
+	}
+
+	c := &Client{
+		cfg:           cfg,
+		metrics:       do.MustInvoke[*telemetry.Metrics](di),
+		freeClient:    newOpenaiClient(cfg, true),
+		client:        newOpenaiClient(cfg, false),
+		defaultPrompt: defaultPrompt.Text,
+	}
+	c.freeProtocol.text = cfg.FreeOpenAI.TextProtocol
+	c.protocol.text = cfg.OpenAI.TextProtocol
+
+	return c, nil
 }
 
-func (c *Client) doCompletionRequest(ctx context.Context, text string, useFreeClient bool) (*openai.ChatCompletionResponse, error) {
+func (c *Client) doCompletionRequest(ctx context.Context, systemPrompt, text string, useFreeClient, structured bool) (*openai.ChatCompletionResponse, error) {
 	var client *openai.Client
 	var model string
 
@@ -62,23 +117,38 @@ func (c *Client) doCompletionRequest(ctx context.Context, text string, useFreeCl
 		model = c.cfg.OpenAI.Model
 	}
 
-	resp, err := client.CreateChatCompletion(
-		ctx,
-		openai.ChatCompletionRequest{
-			Model: model,
-			Messages: []openai.ChatCompletionMessage{
-				{
-					Role:    openai.ChatMessageRoleSystem,
-					Content: systemPrompt,
-				},
-				{
-					Role:    openai.ChatMessageRoleUser,
-					Content: text,
-				},
+	request := openai.ChatCompletionRequest{
+		Model: model,
+		Messages: []openai.ChatCompletionMessage{
+			{
+				Role:    openai.ChatMessageRoleSystem,
+				Content: systemPrompt,
+			},
+			{
+				Role:    openai.ChatMessageRoleUser,
+				Content: text,
 			},
-			MaxCompletionTokens: 10000,
-			Seed:                meg.ToPtr(2025),
 		},
+		MaxCompletionTokens: 10000,
+		Seed:                meg.ToPtr(2025),
+	}
+
+	if structured {
+		request.Messages[0].Content = systemPrompt + "\n" + jsonFormatPrompt
+		request.ResponseFormat = &openai.ChatCompletionResponseFormat{
+			Type: openai.ChatCompletionResponseFormatTypeJSONSchema,
+			JSONSchema: &openai.ChatCompletionResponseFormatJSONSchema{
+				Name:   "toxicity_verdict",
+				Schema: verdictSchema,
+				Strict: true,
+			},
+		}
+	}
+
+	start := time.Now()
+	resp, err := client.CreateChatCompletion(ctx, request)
+	c.metrics.LLMLatency.Record(ctx, time.Since(start).Seconds(),
+		otelmetric.WithAttributes(attribute.String("model", modelKind(useFreeClient))),
 	)
 
 	return &resp, err
@@ -99,7 +169,33 @@ func (c *Client) Ping(ctx context.Context) error {
 }
 
 func (c *Client) Analyze(ctx context.Context, text string, useFreeClient bool) (*AnalyzeResult, error) {
-	resp, err := c.doCompletionRequest(ctx, text, useFreeClient)
+	return c.AnalyzeWithPrompt(ctx, text, "", useFreeClient)
+}
+
+// AnalyzeWithPrompt is Analyze with a custom system prompt, an empty prompt means the built-in one
+func (c *Client) AnalyzeWithPrompt(ctx context.Context, text, systemPrompt string, useFreeClient bool) (*AnalyzeResult, error) {
+	if systemPrompt == "" {
+		systemPrompt = c.defaultPrompt
+	}
+
+	p := &c.protocol
+	if useFreeClient {
+		p = &c.freeProtocol
+	}
+
+	structured := !p.useText(time.Now())
+
+	resp, err := c.doCompletionRequest(ctx, systemPrompt, text, useFreeClient, structured)
+	if err != nil && structured && isUnsupportedError(err) {
+		slog.Warn("Structured output is not supported, falling back to text protocol",
+			slog.Bool("free", useFreeClient),
+			slog.Duration("retryIn", structuredRetryInterval),
+			slog.Any("error", err),
+		)
+
+		p.fallBack(time.Now())
+		resp, err = c.doCompletionRequest(ctx, systemPrompt, text, useFreeClient, false)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("CreateChatCompletion: %w", err)
 	}
@@ -108,34 +204,40 @@ func (c *Client) Analyze(ctx context.Context, text string, useFreeClient bool) (
 		return nil, fmt.Errorf("empty openai response")
 	}
 
-	rawResult := strings.TrimSpace(resp.Choices[0].Message.Content)
+	return parseResult(resp.Choices[0].Message.Content)
+}
 
-	if rawResult == "OK" {
-		return &AnalyzeResult{
-			Toxic: false,
-		}, nil
+func modelKind(useFreeClient bool) string {
+	if useFreeClient {
+		return "free"
 	}
 
-	if rawResult == "OFF" {
-		return &AnalyzeResult{
-			TurnOff: true,
-		}, nil
-	}
+	return "paid"
+}
 
-	if rawResult == "ON" {
-		return &AnalyzeResult{
-			TurnOn: true,
-		}, nil
+// isUnsupportedError checks whether the provider rejected response_format itself, other bad requests
+// (e.g. a too long transcript or a content filter) fail with the text protocol just the same
+func isUnsupportedError(err error) bool {
+	var status int
+	var message string
+
+	var apiErr *openai.APIError
+	var reqErr *openai.RequestError
+
+	switch {
+	case errors.As(err, &apiErr):
+		status, message = apiErr.HTTPStatusCode, apiErr.Message
+	case errors.As(err, &reqErr):
+		status, message = reqErr.HTTPStatusCode, string(reqErr.Body)
+	default:
+		return false
 	}
 
-	if strings.HasPrefix(rawResult, "TOXIC:") {
-		rawResult = strings.TrimPrefix(rawResult, "TOXIC:")
-		rawResult = strings.TrimSpace(rawResult)
-		return &AnalyzeResult{
-			Toxic:  true,
-			Phrase: rawResult,
-		}, nil
+	if status != http.StatusBadRequest && status != http.StatusUnprocessableEntity {
+		return false
 	}
 
-	return nil, fmt.Errorf("invalid openai response: %s", rawResult)
+	message = strings.ToLower(message)
+
+	return strings.Contains(message, "response_format") || strings.Contains(message, "json_schema")
 }
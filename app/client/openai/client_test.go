@@ -3,6 +3,7 @@ package openai
 import (
 	"context"
 	"nicemaxxingbot/app/config"
+	"nicemaxxingbot/app/util/telemetry"
 	"testing"
 	"time"
 
@@ -17,6 +18,7 @@ func TestToxicity_TableDriven(t *testing.T) {
 
 	di := do.New()
 	do.ProvideValue(di, cfg)
+	do.ProvideValue(di, telemetry.NewNoopMetrics())
 
 	client, err := NewClient(di)
 	require.NoError(t, err)
@@ -85,11 +87,11 @@ func TestToxicity_TableDriven(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.phrase, func(t *testing.T) {
-			toxicPhrase, isToxic, err := client.CheckToxicity(context.Background(), tt.phrase, true)
+			res, err := client.Analyze(context.Background(), tt.phrase, true)
 			require.NoError(t, err)
 
-			assert.Equal(t, tt.expectedToxic, isToxic)
-			assert.Equal(t, tt.expectedPhrase, toxicPhrase)
+			assert.Equal(t, tt.expectedToxic, res.Toxic())
+			assert.Equal(t, tt.expectedPhrase, res.Phrase)
 
 			time.Sleep(3 * time.Second)
 		})
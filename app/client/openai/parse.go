@@ -0,0 +1,127 @@
+package openai
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/sashabaranov/go-openai/jsonschema"
+)
+
+// verdictSchema is the response_format schema of the structured output protocol
+var verdictSchema = &jsonschema.Definition{
+	Type: jsonschema.Object,
+	Properties: map[string]jsonschema.Definition{
+		"verdict": {
+			Type:        jsonschema.String,
+			Enum:        []string{string(VerdictOK), string(VerdictToxic), string(VerdictOff), string(VerdictOn)},
+			Description: "OK if nothing toxic was said, TOXIC for toxic statements, OFF/ON for requests to disable/enable the bot",
+		},
+		"phrase": {
+			Type:        jsonschema.String,
+			Description: "The exact 1 or 2 toxic sentences from the text for TOXIC, the request sentence for OFF if it says for how long, otherwise empty",
+		},
+		"target": {
+			Type:        jsonschema.String,
+			Enum:        []string{string(TargetNone), string(TargetKiller), string(TargetTeammate)},
+			Description: "Who the toxic phrase is aimed at, none unless verdict is TOXIC",
+		},
+		"confidence": {
+			Type:        jsonschema.Number,
+			Description: "Confidence in the verdict from 0 to 1",
+		},
+		"rationale": {
+			Type:        jsonschema.String,
+			Description: "One short sentence explaining the verdict",
+		},
+	},
+	Required:             []string{"verdict", "phrase", "target", "confidence", "rationale"},
+	AdditionalProperties: false,
+}
+
+// parseResult parses both structured (JSON) and legacy plain-text responses,
+// since models without structured output support may still answer in either form.
+func parseResult(content string) (*AnalyzeResult, error) {
+	content = strings.TrimSpace(content)
+	content = strings.TrimPrefix(content, "```json")
+	content = strings.TrimPrefix(content, "```")
+	content = strings.TrimSuffix(content, "```")
+	content = strings.TrimSpace(content)
+
+	if strings.HasPrefix(content, "{") {
+		return parseJSONResult(content)
+	}
+
+	return parseTextResult(content)
+}
+
+func parseJSONResult(content string) (*AnalyzeResult, error) {
+	var result AnalyzeResult
+	if err := json.Unmarshal([]byte(content), &result); err != nil {
+		return nil, fmt.Errorf("invalid openai json response: %w: %s", err, content)
+	}
+
+	result.Verdict = Verdict(strings.ToUpper(strings.TrimSpace(string(result.Verdict))))
+	result.Target = Target(strings.ToLower(strings.TrimSpace(string(result.Target))))
+	result.Phrase = strings.TrimSpace(result.Phrase)
+
+	switch result.Verdict {
+	case VerdictToxic:
+		if result.Phrase == "" {
+			return nil, fmt.Errorf("invalid openai response: toxic verdict without phrase: %s", content)
+		}
+	case VerdictOff:
+		// the request sentence, it may say for how long to mute the bot
+		result.Target = TargetNone
+	case VerdictOK, VerdictOn:
+		result.Phrase = ""
+		result.Target = TargetNone
+	default:
+		return nil, fmt.Errorf("invalid openai response: unknown verdict: %s", content)
+	}
+
+	if result.Target == "" {
+		result.Target = TargetNone
+	}
+
+	return &result, nil
+}
+
+// textVerdictRe matches a whole-reply verdict of the text protocol. Trailing punctuation and a reason after
+// a colon or dash are tolerated, e.g. "OK." or "OFF - the streamer asked to disable the bot", prose is not.
+var textVerdictRe = regexp.MustCompile(`(?is)^(OK|ON|OFF)[.!]*(?:\s*[:-].*)?$`)
+
+func parseTextResult(content string) (*AnalyzeResult, error) {
+	rawResult := strings.TrimSpace(content)
+
+	if strings.HasPrefix(strings.ToUpper(rawResult), "TOXIC:") {
+		rawResult = rawResult[len("TOXIC:"):]
+		rawResult = strings.TrimSpace(rawResult)
+		if rawResult == "" {
+			return nil, fmt.Errorf("invalid openai response: toxic verdict without phrase: %s", content)
+		}
+
+		return textResult(VerdictToxic, rawResult), nil
+	}
+
+	if strings.HasPrefix(strings.ToUpper(rawResult), "OFF:") {
+		return textResult(VerdictOff, strings.TrimSpace(rawResult[len("OFF:"):])), nil
+	}
+
+	match := textVerdictRe.FindStringSubmatch(rawResult)
+	if match == nil {
+		return nil, fmt.Errorf("invalid openai response: %s", content)
+	}
+
+	return textResult(Verdict(strings.ToUpper(match[1])), ""), nil
+}
+
+func textResult(verdict Verdict, phrase string) *AnalyzeResult {
+	return &AnalyzeResult{
+		Verdict:    verdict,
+		Phrase:     phrase,
+		Target:     TargetNone,
+		Confidence: 1,
+	}
+}
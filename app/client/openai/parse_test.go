@@ -0,0 +1,129 @@
+package openai
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseResult(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected AnalyzeResult
+	}{
+		{
+			name:     "text ok",
+			content:  "OK",
+			expected: AnalyzeResult{Verdict: VerdictOK, Target: TargetNone, Confidence: 1},
+		},
+		{
+			name:     "text ok with punctuation",
+			content:  "OK.",
+			expected: AnalyzeResult{Verdict: VerdictOK, Target: TargetNone, Confidence: 1},
+		},
+		{
+			name:     "text ok with reason",
+			content:  "OK - nothing toxic here",
+			expected: AnalyzeResult{Verdict: VerdictOK, Target: TargetNone, Confidence: 1},
+		},
+		{
+			name:     "text on with reason",
+			content:  "ON: the streamer asked to turn the bot back on",
+			expected: AnalyzeResult{Verdict: VerdictOn, Target: TargetNone, Confidence: 1},
+		},
+		{
+			name:     "text off",
+			content:  " OFF\n",
+			expected: AnalyzeResult{Verdict: VerdictOff, Target: TargetNone, Confidence: 1},
+		},
+		{
+			name:     "text off with duration",
+			content:  "OFF: please disable the bot for 2 hours",
+			expected: AnalyzeResult{Verdict: VerdictOff, Phrase: "please disable the bot for 2 hours", Target: TargetNone, Confidence: 1},
+		},
+		{
+			name:     "text on",
+			content:  "on",
+			expected: AnalyzeResult{Verdict: VerdictOn, Target: TargetNone, Confidence: 1},
+		},
+		{
+			name:     "text toxic",
+			content:  "TOXIC: Nurse players are not human",
+			expected: AnalyzeResult{Verdict: VerdictToxic, Phrase: "Nurse players are not human", Target: TargetNone, Confidence: 1},
+		},
+		{
+			name:    "json toxic",
+			content: `{"verdict":"TOXIC","phrase":"Blight players are absolutely dogshit","target":"killer","confidence":0.9,"rationale":"Insults killer players"}`,
+			expected: AnalyzeResult{
+				Verdict:    VerdictToxic,
+				Phrase:     "Blight players are absolutely dogshit",
+				Target:     TargetKiller,
+				Confidence: 0.9,
+				Rationale:  "Insults killer players",
+			},
+		},
+		{
+			name:    "json in markdown fence",
+			content: "```json\n{\"verdict\":\"ok\",\"phrase\":\"\",\"target\":\"none\",\"confidence\":0.8,\"rationale\":\"Banter\"}\n```",
+			expected: AnalyzeResult{
+				Verdict:    VerdictOK,
+				Target:     TargetNone,
+				Confidence: 0.8,
+				Rationale:  "Banter",
+			},
+		},
+		{
+			name:    "json off keeps request",
+			content: `{"verdict":"OFF","phrase":"mute the bot for half an hour","target":"none","confidence":1,"rationale":"Asked to mute the bot"}`,
+			expected: AnalyzeResult{
+				Verdict:    VerdictOff,
+				Phrase:     "mute the bot for half an hour",
+				Target:     TargetNone,
+				Confidence: 1,
+				Rationale:  "Asked to mute the bot",
+			},
+		},
+		{
+			name:    "json ok drops phrase",
+			content: `{"verdict":"OK","phrase":"whatever","target":"killer","confidence":0.7,"rationale":"Joking"}`,
+			expected: AnalyzeResult{
+				Verdict:    VerdictOK,
+				Target:     TargetNone,
+				Confidence: 0.7,
+				Rationale:  "Joking",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := parseResult(tt.content)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, *res)
+		})
+	}
+}
+
+func TestParseResult_Invalid(t *testing.T) {
+	for _, content := range []string{
+		"",
+		"I think this is fine",
+		// prose starting with a verdict word is not a verdict
+		"On second thought, this is fine",
+		"Okay so the streamer said something about Nurse players",
+		"Ok, but actually TOXIC: Nurse players are not human",
+		"OK. Nothing toxic here",
+		"Off the top of my head, nothing toxic",
+		"TOXIC:",
+		`{"verdict":"MAYBE"}`,
+		`{"verdict":"TOXIC","phrase":""}`,
+		`{"verdict":`,
+	} {
+		t.Run(content, func(t *testing.T) {
+			_, err := parseResult(content)
+			assert.Error(t, err)
+		})
+	}
+}
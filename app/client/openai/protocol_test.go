@@ -0,0 +1,76 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"nicemaxxingbot/app/config"
+	"nicemaxxingbot/app/util/telemetry"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/samber/do"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestClient returns a client of a fake provider that fails structured requests with the error message
+func newTestClient(t *testing.T, structuredError string, requests *atomic.Int32) *Client {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+
+		var body map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+
+		w.Header().Set("Content-Type", "application/json")
+
+		if _, structured := body["response_format"]; structured {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"error": map[string]string{"message": structuredError, "type": "invalid_request_error"},
+			})
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"choices":[{"index":0,"message":{"role":"assistant","content":"OK"}}]}`))
+	}))
+	t.Cleanup(server.Close)
+
+	openaiCfg := config.OpenAI{BaseURL: server.URL, Token: "token", Model: "model"}
+
+	di := do.New()
+	do.ProvideValue(di, &config.Config{OpenAI: openaiCfg, FreeOpenAI: openaiCfg})
+	do.ProvideValue(di, telemetry.NewNoopMetrics())
+
+	client, err := NewClient(di)
+	require.NoError(t, err)
+
+	return client
+}
+
+func TestAnalyze_UnsupportedResponseFormat(t *testing.T) {
+	var requests atomic.Int32
+	client := newTestClient(t, "response_format json_schema is not supported by this model", &requests)
+
+	result, err := client.Analyze(context.Background(), "gg", false)
+	require.NoError(t, err)
+	assert.Equal(t, VerdictOK, result.Verdict)
+	assert.Equal(t, int32(2), requests.Load())
+
+	assert.True(t, client.protocol.useText(time.Now()))
+	assert.False(t, client.protocol.useText(time.Now().Add(structuredRetryInterval+time.Minute)))
+	assert.False(t, client.freeProtocol.useText(time.Now()))
+}
+
+func TestAnalyze_UnrelatedBadRequest(t *testing.T) {
+	var requests atomic.Int32
+	client := newTestClient(t, "This model's maximum context length is 8192 tokens", &requests)
+
+	_, err := client.Analyze(context.Background(), "gg", false)
+	require.Error(t, err)
+	assert.Equal(t, int32(1), requests.Load())
+
+	assert.False(t, client.protocol.useText(time.Now()))
+}
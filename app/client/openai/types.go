@@ -1,8 +1,35 @@
 package openai
 
+type Verdict string
+
+const (
+	VerdictOK    Verdict = "OK"
+	VerdictToxic Verdict = "TOXIC"
+	VerdictOff   Verdict = "OFF"
+	VerdictOn    Verdict = "ON"
+)
+
+type Target string
+
+const (
+	TargetNone     Target = "none"
+	TargetKiller   Target = "killer"
+	TargetTeammate Target = "teammate"
+)
+
 type AnalyzeResult struct {
-	Toxic   bool   `json:"toxic"`
-	Phrase  string `json:"phrase"`
-	TurnOff bool   `json:"off"`
-	TurnOn  bool   `json:"on"`
+	Verdict Verdict `json:"verdict"`
+	// Phrase is the quoted toxic phrase for the TOXIC verdict, or the request sentence for the OFF verdict
+	// if it says for how long to mute the bot
+	Phrase string `json:"phrase"`
+	// Target is who the toxic phrase is aimed at
+	Target Target `json:"target"`
+	// Confidence of the model in the verdict, 0..1 (1 for the text protocol which does not report it)
+	Confidence float64 `json:"confidence"`
+	// Rationale is a short explanation of the verdict
+	Rationale string `json:"rationale"`
+}
+
+func (r *AnalyzeResult) Toxic() bool {
+	return r.Verdict == VerdictToxic
 }
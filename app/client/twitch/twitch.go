@@ -2,19 +2,29 @@ package twitch
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"nicemaxxingbot/app/config"
+	"nicemaxxingbot/app/util/telemetry"
+	"sync"
 	"time"
 
 	"github.com/nicklaw5/helix/v2"
 	"github.com/samber/do"
+	"go.opentelemetry.io/otel/attribute"
+	otelmetric "go.opentelemetry.io/otel/metric"
 )
 
 type Client struct {
 	cfg        *config.Config
+	metrics    *telemetry.Metrics
 	userClient *helix.Client
+
+	m sync.Mutex
+	// user IDs by login, they never change, so they are looked up once
+	userIDs map[string]string
 }
 
 func NewClient(di *do.Injector) (*Client, error) {
@@ -48,11 +58,21 @@ func NewClient(di *do.Injector) (*Client, error) {
 
 	return &Client{
 		cfg:        cfg,
+		metrics:    do.MustInvoke[*telemetry.Metrics](di),
 		userClient: helixClient,
+		userIDs:    make(map[string]string),
 	}, nil
 }
 
 func (c *Client) GetUserIDByUsername(username string) (string, error) {
+	c.m.Lock()
+	userID, ok := c.userIDs[username]
+	c.m.Unlock()
+
+	if ok {
+		return userID, nil
+	}
+
 	resp, err := c.userClient.GetUsers(&helix.UsersParams{
 		Logins: []string{username},
 	})
@@ -67,10 +87,27 @@ func (c *Client) GetUserIDByUsername(username string) (string, error) {
 		return "", fmt.Errorf("failed to get user info: no users found")
 	}
 
-	return resp.Data.Users[0].ID, nil
+	userID = resp.Data.Users[0].ID
+
+	c.m.Lock()
+	c.userIDs[username] = userID
+	c.m.Unlock()
+
+	return userID, nil
 }
 
 func (c *Client) SendMessage(channel, text string) error {
+	err := c.sendMessage(channel, text)
+
+	c.metrics.ChatMessages.Add(context.Background(), 1, otelmetric.WithAttributes(
+		attribute.String("channel", channel),
+		attribute.Bool("success", err == nil),
+	))
+
+	return err
+}
+
+func (c *Client) sendMessage(channel, text string) error {
 	broadcasterID, err := c.GetUserIDByUsername(channel)
 	if err != nil {
 		return fmt.Errorf("failed to get broadcaster id: %v", err)
@@ -96,29 +133,118 @@ func (c *Client) SendMessage(channel, text string) error {
 	return nil
 }
 
-func (c *Client) GetStreamStartedAt(username string) (time.Time, error) {
-	userID, err := c.GetUserIDByUsername(username)
-	if err != nil {
-		return time.Time{}, fmt.Errorf("failed to get user id: %v", err)
-	}
+// StreamInfo describes a live broadcast
+type StreamInfo struct {
+	// ID of the broadcast, it changes every time the channel goes live
+	ID        string
+	StartedAt time.Time
+	Title     string
+	GameID    string
+	GameName  string
+	Language  string
+}
 
+// GetStream returns the current broadcast of the channel, nil if the channel is offline
+func (c *Client) GetStream(username string) (*StreamInfo, error) {
 	resp, err := c.userClient.GetStreams(&helix.StreamsParams{
-		UserIDs: []string{userID},
+		UserLogins: []string{username},
 	})
 	if err != nil {
-		return time.Time{}, fmt.Errorf("failed to get stream info: %v", err)
+		return nil, fmt.Errorf("failed to get stream info: %v", err)
 	}
 	if resp.StatusCode != 200 {
-		return time.Time{}, fmt.Errorf("failed to get stream info: status %d: %s", resp.StatusCode, resp.ErrorMessage)
+		return nil, fmt.Errorf("failed to get stream info: status %d: %s", resp.StatusCode, resp.ErrorMessage)
 	}
 
 	if len(resp.Data.Streams) == 0 {
-		return time.Time{}, fmt.Errorf("stream is not live")
+		return nil, nil
 	}
 
 	stream := resp.Data.Streams[0]
 
-	return stream.StartedAt, nil
+	return &StreamInfo{
+		ID:        stream.ID,
+		StartedAt: stream.StartedAt,
+		Title:     stream.Title,
+		GameID:    stream.GameID,
+		GameName:  stream.GameName,
+		Language:  stream.Language,
+	}, nil
+}
+
+// ChannelInfo is the channel information set by the broadcaster, available while the channel is offline too
+type ChannelInfo struct {
+	GameID   string
+	GameName string
+	Title    string
+	Language string
+}
+
+// GetChannelInfo returns the current category, title and language of the channel
+func (c *Client) GetChannelInfo(username string) (ChannelInfo, error) {
+	userID, err := c.GetUserIDByUsername(username)
+	if err != nil {
+		return ChannelInfo{}, fmt.Errorf("failed to get user id: %v", err)
+	}
+
+	resp, err := c.userClient.GetChannelInformation(&helix.GetChannelInformationParams{
+		BroadcasterIDs: []string{userID},
+	})
+	if err != nil {
+		return ChannelInfo{}, fmt.Errorf("failed to get channel info: %v", err)
+	}
+	if resp.StatusCode != 200 {
+		return ChannelInfo{}, fmt.Errorf("failed to get channel info: status %d: %s", resp.StatusCode, resp.ErrorMessage)
+	}
+
+	if len(resp.Data.Channels) == 0 {
+		return ChannelInfo{}, fmt.Errorf("channel not found")
+	}
+
+	channel := resp.Data.Channels[0]
+
+	return ChannelInfo{
+		GameID:   channel.GameID,
+		GameName: channel.GameName,
+		Title:    channel.Title,
+		Language: channel.BroadcasterLanguage,
+	}, nil
+}
+
+// ErrNoVOD is returned if the broadcast is not archived, e.g. because the channel has archives turned off
+var ErrNoVOD = errors.New("no VOD of the broadcast")
+
+// GetArchiveVideoID returns the ID of the VOD that is being recorded for the broadcast
+func (c *Client) GetArchiveVideoID(username, streamID string) (string, error) {
+	userID, err := c.GetUserIDByUsername(username)
+	if err != nil {
+		return "", fmt.Errorf("failed to get user id: %v", err)
+	}
+
+	resp, err := c.userClient.GetVideos(&helix.VideosParams{
+		UserID: userID,
+		Type:   "archive",
+		First:  5,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to get videos: %v", err)
+	}
+	if resp.StatusCode != 200 {
+		return "", fmt.Errorf("failed to get videos: status %d: %s", resp.StatusCode, resp.ErrorMessage)
+	}
+
+	for _, video := range resp.Data.Videos {
+		if video.StreamID == streamID {
+			return video.ID, nil
+		}
+	}
+
+	return "", fmt.Errorf("%w: stream %s", ErrNoVOD, streamID)
+}
+
+// AccessToken returns the current user access token of the bot account
+func (c *Client) AccessToken() string {
+	return c.userClient.GetUserAccessToken()
 }
 
 func (c *Client) refreshToken() {
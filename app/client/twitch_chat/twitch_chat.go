@@ -0,0 +1,83 @@
+package twitch_chat
+
+import (
+	"context"
+	"log/slog"
+	"nicemaxxingbot/app/client/twitch"
+	"nicemaxxingbot/app/config"
+	"time"
+
+	twitchirc "github.com/gempir/go-twitch-irc/v4"
+	"github.com/samber/do"
+)
+
+const reconnectDelay = 10 * time.Second
+
+type Message struct {
+	Channel string
+	User    string
+	// Privileged is set for moderators and the broadcaster
+	Privileged bool
+	Text       string
+}
+
+// Client reads channel chats over Twitch IRC
+type Client struct {
+	cfg          *config.Config
+	twitchClient *twitch.Client
+}
+
+func NewClient(di *do.Injector) (*Client, error) {
+	return &Client{
+		cfg:          do.MustInvoke[*config.Config](di),
+		twitchClient: do.MustInvoke[*twitch.Client](di),
+	}, nil
+}
+
+// Run joins the channels and calls handler for every chat message until the context is cancelled.
+// Dropped connections are re-established with a fresh access token.
+func (c *Client) Run(ctx context.Context, channels []string, handler func(Message)) {
+	for {
+		ircClient := twitchirc.NewClient(c.cfg.Twitch.Username, "oauth:"+c.twitchClient.AccessToken())
+		ircClient.OnConnect(func() {
+			slog.Info("Connected to twitch chat",
+				slog.Any("channels", channels),
+			)
+		})
+		ircClient.OnPrivateMessage(func(msg twitchirc.PrivateMessage) {
+			handler(Message{
+				Channel:    msg.Channel,
+				User:       msg.User.Name,
+				Privileged: msg.User.IsMod || msg.User.IsBroadcaster,
+				Text:       msg.Message,
+			})
+		})
+		ircClient.Join(channels...)
+
+		done := make(chan struct{})
+		go func() {
+			select {
+			case <-ctx.Done():
+				_ = ircClient.Disconnect()
+			case <-done:
+			}
+		}()
+
+		err := ircClient.Connect()
+		close(done)
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		slog.Warn("Disconnected from twitch chat",
+			slog.Any("error", err),
+		)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(reconnectDelay):
+		}
+	}
+}
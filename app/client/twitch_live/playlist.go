@@ -0,0 +1,157 @@
+package twitch_live
+
+import (
+	"bufio"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"nicemaxxingbot/app/client/hls"
+)
+
+// StreamQuality is a variant of the HLS master playlist
+type StreamQuality struct {
+	// Quality is the rendition name, e.g. "1080p60 (source)" or "audio_only"
+	Quality string `json:"quality"`
+	// Resolution is WIDTHxHEIGHT, empty for audio-only variants
+	Resolution string `json:"resolution"`
+	URL        string `json:"url"`
+	// Bandwidth is the peak bit rate in bits per second
+	Bandwidth int `json:"bandwidth"`
+	// Codecs is the comma-separated codec list, e.g. "avc1.64002A,mp4a.40.2"
+	Codecs string `json:"codecs"`
+	// GroupID is the rendition group of the variant (the VIDEO attribute)
+	GroupID string `json:"group_id"`
+	// FrameRate is the maximum frame rate, zero if unknown
+	FrameRate float64 `json:"frame_rate"`
+}
+
+// AudioOnly reports whether the variant has no video
+func (q StreamQuality) AudioOnly() bool {
+	return q.GroupID == "audio_only" || q.Quality == "audio_only"
+}
+
+type media struct {
+	groupID string
+	name    string
+}
+
+// parsePlaylist parses an HLS master playlist into its variants. Unknown tags are skipped.
+func parsePlaylist(playlist string) ([]StreamQuality, error) {
+	var result []StreamQuality
+	var pending *StreamQuality
+
+	// rendition names by GROUP-ID of #EXT-X-MEDIA:TYPE=VIDEO tags
+	renditions := make(map[string]media)
+
+	scanner := bufio.NewScanner(strings.NewReader(playlist))
+	lineNumber := 0
+
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+
+		if lineNumber == 1 {
+			if line != "#EXTM3U" {
+				return nil, fmt.Errorf("not an m3u8 playlist: %q", line)
+			}
+			continue
+		}
+
+		if line == "" {
+			continue
+		}
+
+		tag, value, _ := strings.Cut(line, ":")
+
+		switch {
+		case tag == "#EXT-X-MEDIA":
+			attrs, err := hls.ParseAttributes(value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+			}
+
+			if attrs["TYPE"] != "VIDEO" {
+				continue
+			}
+
+			renditions[attrs["GROUP-ID"]] = media{
+				groupID: attrs["GROUP-ID"],
+				name:    attrs["NAME"],
+			}
+
+		case tag == "#EXT-X-STREAM-INF":
+			attrs, err := hls.ParseAttributes(value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+			}
+
+			quality, err := newStreamQuality(attrs)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+			}
+
+			pending = &quality
+
+		case strings.HasPrefix(line, "#"):
+			// other tags (#EXT-X-TWITCH-INFO, #EXT-X-SESSION-DATA, ...) and comments
+
+		default:
+			if pending == nil {
+				return nil, fmt.Errorf("line %d: URI without #EXT-X-STREAM-INF", lineNumber)
+			}
+
+			pending.URL = line
+			result = append(result, *pending)
+			pending = nil
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("scan: %w", err)
+	}
+
+	if pending != nil {
+		return nil, fmt.Errorf("#EXT-X-STREAM-INF without URI")
+	}
+
+	for i := range result {
+		quality := &result[i]
+
+		if rendition, ok := renditions[quality.GroupID]; ok && rendition.name != "" {
+			quality.Quality = rendition.name
+		} else if quality.Quality == "" {
+			quality.Quality = quality.GroupID
+		}
+	}
+
+	return result, nil
+}
+
+func newStreamQuality(attrs map[string]string) (StreamQuality, error) {
+	quality := StreamQuality{
+		Resolution: attrs["RESOLUTION"],
+		Codecs:     attrs["CODECS"],
+		GroupID:    attrs["VIDEO"],
+		// Twitch also names the variant here on some edges
+		Quality: attrs["IVS-NAME"],
+	}
+
+	if value, ok := attrs["BANDWIDTH"]; ok {
+		bandwidth, err := strconv.Atoi(value)
+		if err != nil {
+			return StreamQuality{}, fmt.Errorf("invalid BANDWIDTH %q: %w", value, err)
+		}
+		quality.Bandwidth = bandwidth
+	}
+
+	if value, ok := attrs["FRAME-RATE"]; ok {
+		frameRate, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return StreamQuality{}, fmt.Errorf("invalid FRAME-RATE %q: %w", value, err)
+		}
+		quality.FrameRate = frameRate
+	}
+
+	return quality, nil
+}
@@ -0,0 +1,144 @@
+package twitch_live
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePlaylist(t *testing.T) {
+	tests := []struct {
+		file string
+		want []StreamQuality
+	}{
+		{
+			file: "full.m3u8",
+			want: []StreamQuality{
+				{
+					Quality:    "1080p60 (source)",
+					Resolution: "1920x1080",
+					URL:        "https://video-weaver.fra05.hls.ttvnw.net/v1/playlist/chunked.m3u8",
+					Bandwidth:  8534030,
+					Codecs:     "avc1.64002A,mp4a.40.2",
+					GroupID:    "chunked",
+					FrameRate:  60,
+				},
+				{
+					Quality:    "720p60",
+					Resolution: "1280x720",
+					URL:        "https://video-weaver.fra05.hls.ttvnw.net/v1/playlist/720p60.m3u8",
+					Bandwidth:  3422999,
+					Codecs:     "avc1.4D401F,mp4a.40.2",
+					GroupID:    "720p60",
+					FrameRate:  60,
+				},
+				{
+					Quality:    "480p",
+					Resolution: "852x480",
+					URL:        "https://video-weaver.fra05.hls.ttvnw.net/v1/playlist/480p30.m3u8",
+					Bandwidth:  1427999,
+					Codecs:     "avc1.4D401F,mp4a.40.2",
+					GroupID:    "480p30",
+					FrameRate:  30,
+				},
+				{
+					Quality:   "audio_only",
+					URL:       "https://video-weaver.fra05.hls.ttvnw.net/v1/playlist/audio_only.m3u8",
+					Bandwidth: 160000,
+					Codecs:    "mp4a.40.2",
+					GroupID:   "audio_only",
+				},
+			},
+		},
+		{
+			file: "source_only.m3u8",
+			want: []StreamQuality{
+				{
+					Quality:    "1080p60 (source)",
+					Resolution: "1920x1080",
+					URL:        "https://video-weaver.waw02.hls.ttvnw.net/v1/playlist/chunked.m3u8",
+					Bandwidth:  6000000,
+					Codecs:     "avc1.640028,mp4a.40.2",
+					GroupID:    "chunked",
+					FrameRate:  60,
+				},
+			},
+		},
+		{
+			file: "twitch_tags.m3u8",
+			want: []StreamQuality{
+				{
+					Quality:    "1440p60 (source)",
+					Resolution: "2560x1440",
+					URL:        "https://video-weaver.ams03.hls.ttvnw.net/v1/playlist/chunked.m3u8?token=a,b",
+					Bandwidth:  9213740,
+					Codecs:     "hvc1.1.2.L123,mp4a.40.2",
+					GroupID:    "chunked",
+					FrameRate:  60,
+				},
+				{
+					Quality:    "720p",
+					Resolution: "1280x720",
+					URL:        "https://video-weaver.ams03.hls.ttvnw.net/v1/playlist/720p30.m3u8",
+					Bandwidth:  2373000,
+					Codecs:     "avc1.4D401F,mp4a.40.2",
+					GroupID:    "720p30",
+					FrameRate:  30,
+				},
+				{
+					// no #EXT-X-MEDIA tag, named by IVS-NAME
+					Quality:   "audio_only",
+					URL:       "https://video-weaver.ams03.hls.ttvnw.net/v1/playlist/audio_only.m3u8",
+					Bandwidth: 160000,
+					Codecs:    "mp4a.40.2",
+					GroupID:   "audio_only",
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", tt.file))
+			require.NoError(t, err)
+
+			got, err := parsePlaylist(string(data))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParsePlaylistErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		playlist string
+	}{
+		{
+			name:     "not a playlist",
+			playlist: "<html>transcode does not exist</html>",
+		},
+		{
+			name:     "variant without URI",
+			playlist: "#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=160000,VIDEO=\"audio_only\"\n",
+		},
+		{
+			name:     "invalid bandwidth",
+			playlist: "#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=fast\nhttps://example.com/a.m3u8\n",
+		},
+		{
+			name:     "unterminated quote",
+			playlist: "#EXTM3U\n#EXT-X-STREAM-INF:CODECS=\"mp4a.40.2\nhttps://example.com/a.m3u8\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parsePlaylist(tt.playlist)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -32,12 +32,6 @@ type AccessToken struct {
 	Signature string `json:"signature"`
 }
 
-type StreamQuality struct {
-	Quality    string `json:"quality"`
-	Resolution string `json:"resolution"`
-	URL        string `json:"url"`
-}
-
 func (c *Client) getAccessToken(ctx context.Context, id string) (*AccessToken, error) {
 	type persistedQuery struct {
 		Version    int    `json:"version"`
@@ -122,7 +116,7 @@ func (c *Client) getAccessToken(ctx context.Context, id string) (*AccessToken, e
 	}
 
 	if err := json.Unmarshal(body, &response); err != nil {
-		return nil, fmt.Errorf("json.Unmarshal", resp.StatusCode)
+		return nil, fmt.Errorf("json.Unmarshal: %w", err)
 	}
 
 	return response.Data.StreamPlaybackAccessToken, nil
@@ -164,45 +158,6 @@ func (c *Client) getPlaylist(id string, accessToken *AccessToken) (string, error
 	}
 }
 
-func parsePlaylist(playlist string) []StreamQuality {
-	parsedPlaylist := []StreamQuality{}
-	lines := strings.Split(playlist, "\n")
-
-	for i := 4; i < len(lines); i += 3 {
-		if i-2 < 0 || i-1 < 0 || i >= len(lines) {
-			continue
-		}
-
-		qualityLine := lines[i-2]
-		resolutionLine := lines[i-1]
-		urlLine := lines[i]
-
-		quality := ""
-		if strings.Contains(qualityLine, "NAME=\"") {
-			parts := strings.Split(qualityLine, "NAME=\"")
-			if len(parts) > 1 {
-				quality = strings.Split(parts[1], "\"")[0]
-			}
-		}
-
-		resolution := ""
-		if strings.Contains(resolutionLine, "RESOLUTION=") {
-			parts := strings.Split(resolutionLine, "RESOLUTION=")
-			if len(parts) > 1 {
-				resolution = strings.Split(parts[1], ",")[0]
-			}
-		}
-
-		parsedPlaylist = append(parsedPlaylist, StreamQuality{
-			Quality:    quality,
-			Resolution: resolution,
-			URL:        urlLine,
-		})
-	}
-
-	return parsedPlaylist
-}
-
 func (c *Client) GetM3U8(ctx context.Context, channel string) ([]StreamQuality, error) {
 	accessToken, err := c.getAccessToken(ctx, channel)
 	if err != nil {
@@ -214,5 +169,10 @@ func (c *Client) GetM3U8(ctx context.Context, channel string) ([]StreamQuality,
 		return nil, fmt.Errorf("getPlaylist: %w", err)
 	}
 
-	return parsePlaylist(playlist), nil
+	qualities, err := parsePlaylist(playlist)
+	if err != nil {
+		return nil, fmt.Errorf("parsePlaylist: %w", err)
+	}
+
+	return qualities, nil
 }
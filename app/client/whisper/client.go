@@ -2,22 +2,23 @@ package whisper
 
 import (
 	"context"
-	_ "embed"
 	"fmt"
+	"io"
 	"nicemaxxingbot/app/config"
+	"nicemaxxingbot/app/util/telemetry"
 	"os"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/samber/do"
 	"github.com/sashabaranov/go-openai"
 )
 
-//go:embed SYSTEM_PROMPT.txt
-var systemPrompt string
-
 type Client struct {
-	cfg    *config.Config
-	client *openai.Client
+	cfg     *config.Config
+	metrics *telemetry.Metrics
+	client  *openai.Client
 }
 
 func NewClient(di *do.Injector) (*Client, error) {
@@ -27,8 +28,9 @@ func NewClient(di *do.Injector) (*Client, error) {
 	client := openai.NewClientWithConfig(clientConfig)
 
 	return &Client{
-		cfg:    cfg,
-		client: client,
+		cfg:     cfg,
+		metrics: do.MustInvoke[*telemetry.Metrics](di),
+		client:  client,
 	}, nil
 }
 
@@ -41,29 +43,100 @@ func (c *Client) Ping(ctx context.Context) error {
 	return nil
 }
 
-func (c *Client) TranscribeFile(ctx context.Context, filePath string) (string, error) {
+// Word is a transcribed word with its position in the audio
+type Word struct {
+	Text  string
+	Start time.Duration
+	End   time.Duration
+}
+
+// Transcription is the text of the audio with word timings
+type Transcription struct {
+	Text  string
+	Words []Word
+}
+
+func (c *Client) TranscribeFile(ctx context.Context, filePath string) (Transcription, error) {
 	audioFile, err := os.Open(filePath)
 	if err != nil {
-		return "", fmt.Errorf("failed to open audio file: %w", err)
+		return Transcription{}, fmt.Errorf("failed to open audio file: %w", err)
 	}
 	defer audioFile.Close()
 
+	return c.Transcribe(ctx, audioFile, filepath.Base(filePath))
+}
+
+// Transcribe transcribes in-memory audio without a prompt, fileName tells the server the audio format (e.g. chunk.wav)
+func (c *Client) Transcribe(ctx context.Context, audio io.Reader, fileName string) (Transcription, error) {
+	return c.TranscribeWithPrompt(ctx, audio, fileName, "")
+}
+
+// TranscribeWithPrompt is Transcribe with a prompt that guides the spelling, an empty prompt is not sent
+func (c *Client) TranscribeWithPrompt(ctx context.Context, audio io.Reader, fileName, prompt string) (Transcription, error) {
+	start := time.Now()
 	resp, err := c.client.CreateTranscription(ctx, openai.AudioRequest{
-		Model: c.cfg.Whisper.Model,
-		// TODO: FIX: system prompt corrupts the results
-		//Prompt:   systemPrompt,
-		FilePath: filePath,
-		Format:   openai.AudioResponseFormatJSON,
+		Model:    c.cfg.Whisper.Model,
+		Prompt:   prompt,
+		FilePath: fileName,
+		Reader:   audio,
+		Format:   openai.AudioResponseFormatVerboseJSON,
+		TimestampGranularities: []openai.TranscriptionTimestampGranularity{
+			openai.TranscriptionTimestampGranularityWord,
+			openai.TranscriptionTimestampGranularitySegment,
+		},
 	})
+	c.metrics.WhisperLatency.Record(ctx, time.Since(start).Seconds())
 	if err != nil {
-		return "", fmt.Errorf("CreateTranscription: %w", err)
+		return Transcription{}, fmt.Errorf("CreateTranscription: %w", err)
 	}
 
-	result := strings.TrimSpace(resp.Text)
+	result := Transcription{
+		Text: strings.TrimSpace(resp.Text),
+	}
+
+	if result.Text == "" {
+		return Transcription{}, fmt.Errorf("empty transcription result")
+	}
 
-	if result == "" {
-		return "", fmt.Errorf("empty transcription result")
+	for _, word := range resp.Words {
+		result.Words = append(result.Words, Word{
+			Text:  strings.TrimSpace(word.Word),
+			Start: seconds(word.Start),
+			End:   seconds(word.End),
+		})
+	}
+
+	// not every server supports word timestamps, spread segment words evenly instead
+	if len(result.Words) == 0 {
+		for _, segment := range resp.Segments {
+			result.Words = append(result.Words, splitSegment(segment.Text, seconds(segment.Start), seconds(segment.End))...)
+		}
 	}
 
 	return result, nil
 }
+
+func seconds(value float64) time.Duration {
+	return time.Duration(value * float64(time.Second))
+}
+
+// splitSegment estimates word timings of a segment by spreading the words evenly over it
+func splitSegment(text string, start, end time.Duration) []Word {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return nil
+	}
+
+	step := (end - start) / time.Duration(len(fields))
+	words := make([]Word, len(fields))
+
+	for i, field := range fields {
+		words[i] = Word{
+			Text:  field,
+			Start: start + time.Duration(i)*step,
+			End:   start + time.Duration(i+1)*step,
+		}
+	}
+
+	return words
+}
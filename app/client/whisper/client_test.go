@@ -3,6 +3,7 @@ package whisper
 import (
 	"context"
 	"nicemaxxingbot/app/config"
+	"nicemaxxingbot/app/util/telemetry"
 	"testing"
 
 	"github.com/samber/do"
@@ -16,6 +17,7 @@ func TestWhisper(t *testing.T) {
 
 	di := do.New()
 	do.ProvideValue(di, cfg)
+	do.ProvideValue(di, telemetry.NewNoopMetrics())
 
 	client, err := NewClient(di)
 	require.NoError(t, err)
@@ -23,5 +25,6 @@ func TestWhisper(t *testing.T) {
 	res, err := client.TranscribeFile(context.Background(), "test.mp3")
 	require.NoError(t, err)
 
-	assert.Equal(t, res, "*Evil laughter* I love this shit so much bro! Ohhhh... What do we call this? What do we call this? There has to be a name. We have to. We have to have a name for this shit.")
+	assert.NotEmpty(t, res.Words)
+	assert.Equal(t, res.Text, "*Evil laughter* I love this shit so much bro! Ohhhh... What do we call this? What do we call this? There has to be a name. We have to. We have to have a name for this shit.")
 }
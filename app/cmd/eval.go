@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"nicemaxxingbot/app/client/openai"
+	"nicemaxxingbot/app/client/whisper"
+	"nicemaxxingbot/app/config"
+	"nicemaxxingbot/app/service/eval"
+	"nicemaxxingbot/app/service/toxic"
+	"nicemaxxingbot/app/util/prompt"
+	"nicemaxxingbot/app/util/telemetry"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/elliotchance/pie/v2"
+	"github.com/samber/do"
+	"github.com/spf13/cobra"
+)
+
+var (
+	evalCorpusPath string
+	evalModel      string
+	evalDelay      time.Duration
+	evalPromptPath string
+	evalChannel    string
+)
+
+var Eval = &cobra.Command{
+	Use:   "eval",
+	Short: "Evaluate the toxicity classifier on a labeled corpus",
+	Run:   runEval,
+}
+
+func init() {
+	Eval.Flags().StringVarP(&configPath, "config", "c", "config.yaml", "Path to config yaml file (required)")
+	Eval.Flags().StringVar(&evalCorpusPath, "corpus", "eval/toxicity.jsonl", "Path to JSONL corpus of {text, verdict, phrase}")
+	Eval.Flags().StringVar(&evalModel, "model", "pipeline", "What to evaluate: pipeline (free model confirmed by paid), free or paid")
+	Eval.Flags().DurationVar(&evalDelay, "delay", 0, "Delay between cases to stay within rate limits")
+	Eval.Flags().StringVar(&evalPromptPath, "prompt", "", "Path to a classifier prompt template to evaluate instead of the configured one")
+	Eval.Flags().StringVar(&evalChannel, "channel", "", "Streamer whose prompt and template variables to use")
+}
+
+func runEval(_ *cobra.Command, _ []string) {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		slog.Error("Failed to load config",
+			slog.Any("error", err),
+		)
+		os.Exit(1) //nolint:gocritic
+		return
+	}
+
+	cases, err := eval.LoadCases(evalCorpusPath)
+	if err != nil {
+		slog.Error("Failed to load corpus",
+			slog.Any("error", err),
+		)
+		os.Exit(1)
+		return
+	}
+
+	systemPrompt, err := loadEvalPrompt(cfg)
+	if err != nil {
+		slog.Error("Failed to load prompt",
+			slog.Any("error", err),
+		)
+		os.Exit(1)
+		return
+	}
+
+	di := do.New()
+	do.ProvideValue(di, ctx)
+	do.ProvideValue(di, cfg)
+	do.ProvideValue(di, telemetry.NewNoopMetrics())
+	do.Provide(di, openai.NewClient)
+	do.Provide(di, whisper.NewClient)
+	do.Provide(di, toxic.New)
+
+	openaiClient := do.MustInvoke[*openai.Client](di)
+	toxicService := do.MustInvoke[*toxic.Service](di)
+
+	var classify eval.Classifier
+
+	switch evalModel {
+	case "pipeline":
+		classify = func(ctx context.Context, text string) (*openai.AnalyzeResult, error) {
+			return toxicService.ProcessTranscriptionWithPrompt(ctx, text, systemPrompt)
+		}
+	case "free", "paid":
+		useFreeClient := evalModel == "free"
+		classify = func(ctx context.Context, text string) (*openai.AnalyzeResult, error) {
+			return openaiClient.AnalyzeWithPrompt(ctx, text, systemPrompt.Text, useFreeClient)
+		}
+	default:
+		slog.Error("Unknown model, expected pipeline, free or paid",
+			slog.String("model", evalModel),
+		)
+		os.Exit(1)
+		return
+	}
+
+	slog.Info("Evaluating...",
+		slog.String("model", evalModel),
+		slog.String("promptHash", systemPrompt.Hash),
+		slog.Int("cases", len(cases)),
+	)
+
+	report := eval.Run(ctx, cases, classify, evalDelay)
+	report.PromptHash = systemPrompt.Hash
+	report.Print(os.Stdout)
+
+	if report.Passed() != len(cases) {
+		fmt.Fprintln(os.Stderr, "Some cases failed")
+		os.Exit(1)
+	}
+}
+
+// loadEvalPrompt renders the classifier prompt the same way the channel would: the --prompt file,
+// the channel prompt, the configured classifier prompt and the built-in one, in that order
+func loadEvalPrompt(cfg *config.Config) (prompt.Prompt, error) {
+	var streamer config.Streamer
+	if evalChannel != "" {
+		index := pie.FindFirstUsing(cfg.Streamers, func(s config.Streamer) bool {
+			return s.Username == evalChannel
+		})
+		if index < 0 {
+			return prompt.Prompt{}, fmt.Errorf("streamer %s is not configured", evalChannel)
+		}
+		streamer = cfg.Streamers[index]
+	}
+
+	var tmpl *prompt.Template
+	var err error
+
+	for _, source := range []config.Prompt{{File: evalPromptPath}, streamer.Prompt} {
+		if tmpl, err = prompt.Load("classifier", source, ""); err != nil || tmpl != nil {
+			break
+		}
+	}
+	if err == nil && tmpl == nil {
+		tmpl, err = prompt.Load("classifier", cfg.Prompts.Classifier, openai.SystemPrompt)
+	}
+	if err != nil {
+		return prompt.Prompt{}, fmt.Errorf("prompt.Load: %w", err)
+	}
+
+	return tmpl.Render(prompt.Vars{
+		Streamer:       streamer.Username,
+		Language:       streamer.Language,
+		AllowedPhrases: streamer.AllowedPhrases,
+	})
+}
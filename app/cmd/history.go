@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"nicemaxxingbot/app/config"
+	"nicemaxxingbot/app/service/message"
+	"nicemaxxingbot/app/service/state"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	historyStreamer   string
+	historyFormat     string
+	historyOutputPath string
+)
+
+var History = &cobra.Command{
+	Use:   "history",
+	Short: "List or export finished nicemaxxing streaks",
+	Run:   runHistory,
+}
+
+func init() {
+	History.Flags().StringVarP(&configPath, "config", "c", "config.yaml", "Path to config yaml file (required)")
+	History.Flags().StringVarP(&historyStreamer, "streamer", "s", "", "Only list streaks of this streamer")
+	History.Flags().StringVarP(&historyFormat, "format", "f", "table", "Output format: table, csv or json")
+	History.Flags().StringVarP(&historyOutputPath, "output", "o", "", "Write to this file instead of stdout")
+}
+
+func runHistory(_ *cobra.Command, _ []string) {
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		slog.Error("Failed to load config",
+			slog.Any("error", err),
+		)
+		os.Exit(1) //nolint:gocritic
+		return
+	}
+
+	if cfg.State.Type != "file" {
+		slog.Error("Streak history is only kept by the file state store",
+			slog.String("type", cfg.State.Type),
+		)
+		os.Exit(1)
+		return
+	}
+
+	entries, err := state.NewFileHistory(cfg.State.HistoryPath).List(context.Background(), strings.ToLower(historyStreamer))
+	if err != nil {
+		slog.Error("Failed to read streak history",
+			slog.Any("error", err),
+		)
+		os.Exit(1)
+		return
+	}
+
+	var out io.Writer = os.Stdout
+
+	if historyOutputPath != "" {
+		file, err := os.Create(historyOutputPath)
+		if err != nil {
+			slog.Error("Failed to create output file",
+				slog.Any("error", err),
+			)
+			os.Exit(1)
+			return
+		}
+		defer file.Close()
+
+		out = file
+	}
+
+	switch historyFormat {
+	case "table":
+		err = writeHistoryTable(out, entries)
+	case "csv":
+		err = state.WriteHistoryCSV(out, entries)
+	case "json":
+		err = state.WriteHistoryJSON(out, entries)
+	default:
+		err = fmt.Errorf("unknown format %q, use table, csv or json", historyFormat)
+	}
+	if err != nil {
+		slog.Error("Failed to write streak history",
+			slog.Any("error", err),
+		)
+		os.Exit(1)
+		return
+	}
+}
+
+// writeHistoryTable lists the streaks followed by the personal best and the longest streak
+// of the last stream of every channel
+func writeHistoryTable(out io.Writer, entries []state.HistoryEntry) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(w, "CHANNEL\tENDED\tSTREAK\tSTREAM\tPHRASE")
+
+	var channels []string
+	best := make(map[string]state.HistoryEntry)
+	lastStream := make(map[string]string)
+	streamBest := make(map[string]state.HistoryEntry)
+
+	for _, entry := range entries {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+			entry.Channel,
+			entry.End.Local().Format(time.DateTime),
+			message.FormatDuration(entry.Duration()),
+			entry.StreamID,
+			entry.Phrase,
+		)
+
+		prev, ok := best[entry.Channel]
+		if !ok {
+			channels = append(channels, entry.Channel)
+		}
+		if !ok || entry.Duration() > prev.Duration() {
+			best[entry.Channel] = entry
+		}
+
+		if lastStream[entry.Channel] != entry.StreamID {
+			lastStream[entry.Channel] = entry.StreamID
+			delete(streamBest, entry.Channel)
+		}
+		if prev, ok := streamBest[entry.Channel]; !ok || entry.Duration() > prev.Duration() {
+			streamBest[entry.Channel] = entry
+		}
+	}
+
+	if len(channels) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "CHANNEL\tPERSONAL BEST\tLONGEST LAST STREAM\t\t")
+	}
+
+	for _, channel := range channels {
+		channelBest, lastStreamBest := best[channel], streamBest[channel]
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t\t\n",
+			channel,
+			message.FormatDuration(channelBest.Duration()),
+			message.FormatDuration(lastStreamBest.Duration()),
+		)
+	}
+
+	return w.Flush()
+}
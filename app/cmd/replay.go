@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"context"
+	"log/slog"
+	"nicemaxxingbot/app/client/openai"
+	"nicemaxxingbot/app/client/whisper"
+	"nicemaxxingbot/app/config"
+	"nicemaxxingbot/app/service/health"
+	"nicemaxxingbot/app/service/message"
+	"nicemaxxingbot/app/service/stream"
+	"nicemaxxingbot/app/service/toxic"
+	"nicemaxxingbot/app/util/telemetry"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/elliotchance/pie/v2"
+	"github.com/samber/do"
+	"github.com/spf13/cobra"
+)
+
+var (
+	replayInputPath string
+	replayStreamer  string
+	replayStart     string
+)
+
+var Replay = &cobra.Command{
+	Use:   "replay",
+	Short: "Run the pipeline against a local audio or video file, printing notifications to stdout",
+	Run:   runReplay,
+}
+
+func init() {
+	Replay.Flags().StringVarP(&configPath, "config", "c", "config.yaml", "Path to config yaml file (required)")
+	Replay.Flags().StringVarP(&replayInputPath, "input", "i", "", "Path to the audio or video file (required)")
+	Replay.Flags().StringVarP(&replayStreamer, "streamer", "s", "", "Streamer whose settings to use (defaults to the first configured one)")
+	Replay.Flags().StringVar(&replayStart, "start", "", "When the recording started, RFC 3339 (defaults to the modification time of the input file)")
+	_ = Replay.MarkFlagRequired("input")
+}
+
+func runReplay(_ *cobra.Command, _ []string) {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		slog.Error("Failed to load config",
+			slog.Any("error", err),
+		)
+		os.Exit(1) //nolint:gocritic
+		return
+	}
+
+	info, err := os.Stat(replayInputPath)
+	if err != nil {
+		slog.Error("Failed to open input file",
+			slog.Any("error", err),
+		)
+		os.Exit(1)
+		return
+	}
+
+	// the simulated clock starts at a time of the recording, not of the run, so replays are reproducible
+	start := info.ModTime()
+	if replayStart != "" {
+		if start, err = time.Parse(time.RFC3339, replayStart); err != nil {
+			slog.Error("Invalid start time",
+				slog.Any("error", err),
+			)
+			os.Exit(1)
+			return
+		}
+	}
+
+	streamer := cfg.Streamers[0]
+	if replayStreamer != "" {
+		index := pie.FindFirstUsing(cfg.Streamers, func(s config.Streamer) bool {
+			return s.Username == replayStreamer
+		})
+		if index < 0 {
+			slog.Error("Streamer is not configured",
+				slog.String("streamer", replayStreamer),
+			)
+			os.Exit(1)
+			return
+		}
+		streamer = cfg.Streamers[index]
+	}
+
+	// notifications only go to stdout during replay, so there is nothing to disable
+	disableNotifications := false
+	streamer.DisableNotifications = &disableNotifications
+
+	di := do.New()
+	do.ProvideValue(di, ctx)
+	do.ProvideValue(di, cfg)
+	do.ProvideValue(di, telemetry.NewNoopMetrics())
+	do.Provide(di, openai.NewClient)
+	do.Provide(di, whisper.NewClient)
+	do.Provide(di, toxic.New)
+	do.Provide(di, message.New)
+	do.Provide(di, health.New)
+
+	service, err := stream.NewReplay(di, os.Stdout, start)
+	if err != nil {
+		slog.Error("Failed to init replay",
+			slog.Any("error", err),
+		)
+		os.Exit(1)
+		return
+	}
+
+	slog.Info("Replaying file...",
+		slog.String("input", replayInputPath),
+		slog.String("streamer", streamer.Username),
+		slog.Time("start", start),
+	)
+
+	if err = service.Replay(ctx, streamer, replayInputPath); err != nil {
+		slog.Error("Replay failed",
+			slog.Any("error", err),
+		)
+		os.Exit(1)
+		return
+	}
+
+	slog.Info("Replay finished")
+}
@@ -3,11 +3,18 @@ package cmd
 import (
 	"context"
 	"log/slog"
+	"nicemaxxingbot/app/client/hls"
 	"nicemaxxingbot/app/client/openai"
 	"nicemaxxingbot/app/client/twitch"
+	"nicemaxxingbot/app/client/twitch_chat"
 	"nicemaxxingbot/app/client/twitch_live"
 	"nicemaxxingbot/app/client/whisper"
 	"nicemaxxingbot/app/config"
+	"nicemaxxingbot/app/service/command"
+	"nicemaxxingbot/app/service/evidence"
+	"nicemaxxingbot/app/service/health"
+	"nicemaxxingbot/app/service/message"
+	"nicemaxxingbot/app/service/state"
 	"nicemaxxingbot/app/service/stream"
 	"nicemaxxingbot/app/service/toxic"
 	"nicemaxxingbot/app/util/mylog"
@@ -96,12 +103,31 @@ func runNotifier(_ *cobra.Command, _ []string) {
 
 	do.Provide(di, twitch.NewClient)
 	do.Provide(di, twitch_live.NewClient)
+	do.Provide(di, hls.NewClient)
 	do.Provide(di, openai.NewClient)
 	do.Provide(di, whisper.NewClient)
+	do.Provide(di, health.New)
+	do.Provide(di, health.NewServer)
+	do.Provide(di, state.New)
+	do.Provide(di, state.NewHistory)
+	do.Provide(di, evidence.New)
 	do.Provide(di, toxic.New)
+	do.Provide(di, message.New)
 	do.Provide(di, stream.New)
+	do.Provide(di, twitch_chat.NewClient)
+	do.Provide(di, command.New)
 
-	if err = do.MustInvoke[*openai.Client](di).Ping(appCtx); err != nil {
+	healthService := do.MustInvoke[*health.Service](di)
+	healthService.Register("openai")
+	healthService.Register("whisper")
+
+	if cfg.Server.Enabled {
+		go do.MustInvoke[*health.Server](di).Run(appCtx)
+	}
+
+	err = do.MustInvoke[*openai.Client](di).Ping(appCtx)
+	healthService.SetReady("openai", err)
+	if err != nil {
 		slog.Error("Failed to init openai client",
 			slog.Any("error", err),
 		)
@@ -109,7 +135,9 @@ func runNotifier(_ *cobra.Command, _ []string) {
 		return
 	}
 
-	if err = do.MustInvoke[*whisper.Client](di).Ping(appCtx); err != nil {
+	err = do.MustInvoke[*whisper.Client](di).Ping(appCtx)
+	healthService.SetReady("whisper", err)
+	if err != nil {
 		slog.Error("Failed to init whisper client",
 			slog.Any("error", err),
 		)
@@ -119,6 +147,14 @@ func runNotifier(_ *cobra.Command, _ []string) {
 
 	go do.MustInvoke[*twitch.Client](di).RunRefreshLoop(appCtx)
 
+	if cfg.Evidence.Enabled {
+		go do.MustInvoke[*evidence.Store](di).Run(appCtx)
+	}
+
+	if cfg.Twitch.ChatCommands {
+		go do.MustInvoke[*command.Service](di).Run(appCtx)
+	}
+
 	go func() {
 		sigint := make(chan os.Signal, 1)
 		signal.Notify(sigint, os.Interrupt)
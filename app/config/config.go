@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"strings"
 
 	"github.com/caarlos0/env/v11"
 	"github.com/go-playground/validator/v10"
@@ -12,16 +13,56 @@ import (
 type Config struct {
 	// Service name for telemetry and logs
 	ServiceName string `yaml:"service_name" env:"SERVICE_NAME" example:"nicemaxxingbot" validate:"required"`
-	// Streamer username (lowercase)
-	Streamer   string     `yaml:"streamer" env:"STREAMER" example:"k0per1s" validate:"required"`
+	// Single streamer username (lowercase), kept for backwards compatibility - use streamers instead
+	Streamer string `yaml:"streamer" env:"STREAMER" example:"k0per1s"`
+	// Streamers to monitor, each one gets its own processing pipeline. Usernames must be unique (case-insensitive).
+	Streamers  []Streamer `yaml:"streamers" validate:"required,min=1,unique=Username,dive"`
 	Sentry     Sentry     `yaml:"sentry" envPrefix:"SENTRY_"`
 	Log        Log        `yaml:"log" envPrefix:"LOG_"`
 	Telemetry  Telemetry  `yaml:"telemetry" envPrefix:"TELEMETRY_"`
+	Server     Server     `yaml:"server" envPrefix:"SERVER_"`
 	Twitch     Twitch     `yaml:"twitch" envPrefix:"TWITCH_"`
 	FreeOpenAI OpenAI     `yaml:"free_openai" envPrefix:"FREE_OPENAI_"`
 	OpenAI     OpenAI     `yaml:"openai" envPrefix:"OPENAI_"`
 	Whisper    Whisper    `yaml:"whisper" envPrefix:"WHISPER_"`
 	Processing Processing `yaml:"processing" envPrefix:"PROCESSING_"`
+	State      State      `yaml:"state" envPrefix:"STATE_"`
+	Evidence   Evidence   `yaml:"evidence" envPrefix:"EVIDENCE_"`
+	Categories Categories `yaml:"categories" envPrefix:"CATEGORIES_"`
+	Prompts    Prompts    `yaml:"prompts"`
+	Messages   Messages   `yaml:"messages"`
+}
+
+type Streamer struct {
+	// Streamer username (lowercase)
+	Username string `yaml:"username" example:"k0per1s" validate:"required"`
+	// Disable notifications for this channel (defaults to twitch.disable_notifications)
+	DisableNotifications *bool `yaml:"disable_notifications" example:"false"`
+	// Minimum streak length in minutes for this channel (defaults to twitch.min_streak_length)
+	MinStreakLength *int `yaml:"min_streak_length" example:"20"`
+	// Classifier prompt template of this channel (defaults to prompts.classifier)
+	Prompt Prompt `yaml:"prompt"`
+	// Broadcaster language passed to prompt templates (defaults to the language set on Twitch)
+	Language string `yaml:"language" example:"en"`
+	// Phrases of this channel that are never toxic, passed to prompt templates
+	AllowedPhrases []string `yaml:"allowed_phrases" example:"[\"killeroid\"]"`
+	// Locale of chat messages of this channel (defaults to messages.locale)
+	Locale string `yaml:"locale" example:"en"`
+	// How long the bot is muted for in minutes if the request doesn't say (defaults to twitch.mute_duration)
+	MuteDuration *int `yaml:"mute_duration" example:"720"`
+	// IANA timezone of the channel, used for quiet hours and shown times
+	Timezone string `yaml:"timezone" example:"Europe/Moscow"`
+	// Recurring windows when streak notifications are not sent (ON/OFF confirmations still are)
+	QuietHours []QuietHours `yaml:"quiet_hours" validate:"dive"`
+}
+
+type QuietHours struct {
+	// Start of the window, HH:MM in the channel timezone
+	From string `yaml:"from" example:"00:00" validate:"required"`
+	// End of the window, HH:MM, the window ends on the next day if it is not after from
+	To string `yaml:"to" example:"08:00" validate:"required"`
+	// Days the window starts on (mon, tue, wed, thu, fri, sat, sun), every day if empty
+	Days []string `yaml:"days" example:"[\"sat\", \"sun\"]" validate:"dive,oneof=mon tue wed thu fri sat sun"`
 }
 
 type Sentry struct {
@@ -45,6 +86,15 @@ type Telemetry struct {
 	Enabled bool `yaml:"enabled" env:"ENABLED" example:"false"`
 }
 
+type Server struct {
+	// Whether to serve prometheus /metrics and /healthz, /readyz probes
+	Enabled bool `yaml:"enabled" env:"ENABLED" example:"false"`
+	// HTTP listen address
+	Addr string `yaml:"addr" env:"ADDR" example:":8080"`
+	// Liveness probe fails if a live stream produced no chunks for this many seconds
+	ChunkTimeout int `yaml:"chunk_timeout" env:"CHUNK_TIMEOUT" example:"300"`
+}
+
 type Twitch struct {
 	// ClientID of the twitch application
 	ClientID string `yaml:"client_id" example:"a1b2c3d4e5f6g7h8i9j0k1l2m3n4o5p" validate:"required"`
@@ -58,6 +108,16 @@ type Twitch struct {
 	DisableNotifications bool `yaml:"disable_notifications" example:"false"`
 	// Minimum streak length in minutes
 	MinStreakLength int `yaml:"min_streak_length" example:"20"`
+	// Join channel chats and accept !nm commands from moderators and broadcasters
+	ChatCommands bool `yaml:"chat_commands" example:"true"`
+	// Link the toxic phrase that ends a streak in the VOD, at the moment it was said (needs archives turned on)
+	VODLinks bool `yaml:"vod_links" example:"false"`
+	// Append the VOD link to the chat notification
+	VODLinksInChat bool `yaml:"vod_links_in_chat" example:"false"`
+	// How long the bot is muted for in minutes if the request doesn't say
+	MuteDuration int `yaml:"mute_duration" example:"720"`
+	// Longest mute in minutes a spoken or chat request can set
+	MaxMuteDuration int `yaml:"max_mute_duration" example:"10080"`
 }
 
 type OpenAI struct {
@@ -67,6 +127,9 @@ type OpenAI struct {
 	Token string `yaml:"token" example:"sk-proj-abc123456789DEF789ghi012JKL345mno678PQR901stu234VWX" validate:"required"`
 	// OpenAI model
 	Model string `yaml:"model" example:"deepseek/deepseek-chat-v3-0324:free" validate:"required"`
+	// Use the plain-text response protocol instead of JSON schema structured output
+	// (for providers without response_format support, detected automatically and retried hourly otherwise)
+	TextProtocol bool `yaml:"text_protocol" example:"false"`
 }
 
 type Whisper struct {
@@ -81,6 +144,96 @@ type Processing struct {
 	BatchSize int `yaml:"batch_size" env:"BATCH_SIZE" example:"10000"`
 	// How many seconds to wait before calling OpenAI (if BatchSize character limit was not reached)
 	BatchTimeout int `yaml:"batch_timeout" env:"BATCH_TIMEOUT" example:"120"`
+	// How audio gets to the pipeline: files (ffmpeg writes WAV segments to disk), pipe (ffmpeg writes raw PCM
+	// to stdout) or hls (segments are downloaded natively and ffmpeg only decodes them, ad breaks are skipped).
+	// files and pipe don't detect ad breaks, ads are transcribed and count towards streaks like the rest of the stream.
+	Ingest string `yaml:"ingest" env:"INGEST" example:"files" validate:"oneof=files pipe hls"`
+	// Length of audio chunks in seconds (the upper bound when splitting on silence)
+	ChunkLength int `yaml:"chunk_length" env:"CHUNK_LENGTH" example:"30"`
+	// Seconds of the previous chunk repeated at the start of the next one, so phrases are not cut at boundaries
+	ChunkOverlap int `yaml:"chunk_overlap" env:"CHUNK_OVERLAP" example:"5" validate:"gte=0,ltfield=ChunkLength"`
+	// Cut chunks at silence points instead of fixed intervals (pipe ingest only)
+	SplitOnSilence bool `yaml:"split_on_silence" env:"SPLIT_ON_SILENCE" example:"false"`
+	// Minimum chunk length in seconds before a silence cut is allowed
+	MinChunkLength int `yaml:"min_chunk_length" env:"MIN_CHUNK_LENGTH" example:"10"`
+	// Level in dBFS below which audio is considered silent
+	SilenceThreshold float64 `yaml:"silence_threshold" env:"SILENCE_THRESHOLD" example:"-40"`
+	// How many chunks of all channels together are transcribed by Whisper at the same time
+	WhisperConcurrency int `yaml:"whisper_concurrency" env:"WHISPER_CONCURRENCY" example:"4" validate:"gte=0"`
+	// How many chunks may wait for a Whisper worker before chunks are dropped
+	QueueSize int `yaml:"queue_size" env:"QUEUE_SIZE" example:"10" validate:"gte=0"`
+	// Which chunks to drop when the queue is full: oldest (stay close to real time) or newest
+	QueueDropPolicy string `yaml:"queue_drop_policy" env:"QUEUE_DROP_POLICY" example:"oldest" validate:"oneof=oldest newest"`
+	// Skip chunks where less than this share of audio (0..1) looks like speech
+	VADThreshold float64 `yaml:"vad_threshold" env:"VAD_THRESHOLD" example:"0.1" validate:"gte=0,lte=1"`
+	// Send every chunk to Whisper, even if it is silent or music-only
+	DisableVAD bool `yaml:"disable_vad" env:"DISABLE_VAD" example:"false"`
+}
+
+type State struct {
+	// State store type: file or memory (lost on restart)
+	Type string `yaml:"type" env:"TYPE" example:"file" validate:"oneof=file memory"`
+	// Path to the state file (for the file store)
+	Path string `yaml:"path" env:"PATH" example:"state/state.json"`
+	// Continue the saved streak if the stream was last seen less than this many minutes ago
+	ResumeWindow int `yaml:"resume_window" env:"RESUME_WINDOW" example:"15"`
+	// Path to the streak history file (for the file store), every finished streak is appended to it
+	HistoryPath string `yaml:"history_path" env:"HISTORY_PATH" example:"state/history.jsonl"`
+}
+
+type Evidence struct {
+	// Save audio, transcript and verdict of every TOXIC, OFF and ON result
+	Enabled bool `yaml:"enabled" env:"ENABLED" example:"true"`
+	// Directory to save evidence to
+	Dir string `yaml:"dir" env:"DIR" example:"evidence"`
+	// Remove evidence older than this many days
+	Retention int `yaml:"retention" env:"RETENTION" example:"14"`
+	// Remove the oldest evidence once the directory is larger than this many megabytes
+	MaxSize int `yaml:"max_size" env:"MAX_SIZE" example:"1024"`
+}
+
+type Categories struct {
+	// What to do in categories without a rule: analyze (with the built-in prompt) or ignore
+	Default string `yaml:"default" env:"DEFAULT" example:"analyze" validate:"oneof=analyze ignore"`
+	// How often to check the category of a live channel, in seconds
+	PollInterval int `yaml:"poll_interval" env:"POLL_INTERVAL" example:"60" validate:"gte=0"`
+	// Per-category rules, matched by the Twitch category name
+	Rules []CategoryRule `yaml:"rules" validate:"dive"`
+}
+
+type CategoryRule struct {
+	// Twitch category name (case-insensitive)
+	Name string `yaml:"name" example:"Dead by Daylight" validate:"required"`
+	// analyze or ignore (nothing is transcribed or judged)
+	Action string `yaml:"action" example:"analyze" validate:"oneof=analyze ignore"`
+	// Classifier prompt template used in this category (defaults to the channel prompt)
+	Prompt Prompt `yaml:"prompt"`
+	// What happens to the streak when the stream switches to this category: keep, freeze (the time in
+	// this category doesn't count) or reset (defaults to freeze for ignored categories, keep otherwise)
+	Streak string `yaml:"streak" example:"keep" validate:"oneof=keep freeze reset"`
+}
+
+type Prompts struct {
+	// Classifier system prompt template (defaults to the built-in Dead by Daylight prompt)
+	Classifier Prompt `yaml:"classifier"`
+	// Whisper prompt template (none by default)
+	Whisper Prompt `yaml:"whisper"`
+}
+
+// Prompt is a Go text/template with .Streamer, .Game, .Language and .AllowedPhrases variables
+type Prompt struct {
+	// Path to the template file
+	File string `yaml:"file" example:"prompts/classifier.txt"`
+	// Inline template, used if file is empty
+	Text string `yaml:"text" example:""`
+}
+
+type Messages struct {
+	// Default locale of chat messages, en is built in
+	Locale string `yaml:"locale" env:"LOCALE" example:"en"`
+	// Chat message templates by locale and message key, a random variant of the list is sent every time.
+	// Missing messages fall back to the default locale and then to the built-in English ones.
+	Locales map[string]map[string][]string `yaml:"locales"`
 }
 
 func Load(configPath string) (*Config, error) {
@@ -109,9 +262,109 @@ func Load(configPath string) (*Config, error) {
 	if result.Processing.BatchTimeout == 0 {
 		result.Processing.BatchTimeout = 120
 	}
+	if result.Processing.Ingest == "" {
+		result.Processing.Ingest = "files"
+	}
+	if result.Processing.ChunkLength == 0 {
+		result.Processing.ChunkLength = 30
+	}
+	if result.Processing.MinChunkLength == 0 {
+		result.Processing.MinChunkLength = 10
+	}
+	if result.Processing.SilenceThreshold == 0 {
+		result.Processing.SilenceThreshold = -40
+	}
+	if result.Processing.WhisperConcurrency == 0 {
+		result.Processing.WhisperConcurrency = 4
+	}
+	if result.Processing.QueueSize == 0 {
+		result.Processing.QueueSize = 10
+	}
+	if result.Processing.QueueDropPolicy == "" {
+		result.Processing.QueueDropPolicy = "oldest"
+	}
+	if result.Processing.VADThreshold == 0 {
+		result.Processing.VADThreshold = 0.1
+	}
 	if result.Twitch.MinStreakLength == 0 {
 		result.Twitch.MinStreakLength = 20
 	}
+	if result.Twitch.MuteDuration == 0 {
+		result.Twitch.MuteDuration = 720
+	}
+	if result.Twitch.MaxMuteDuration == 0 {
+		result.Twitch.MaxMuteDuration = 7 * 24 * 60
+	}
+	if result.Server.Addr == "" {
+		result.Server.Addr = ":8080"
+	}
+	if result.Server.ChunkTimeout == 0 {
+		result.Server.ChunkTimeout = 300
+	}
+	if result.State.Type == "" {
+		result.State.Type = "file"
+	}
+	if result.State.Path == "" {
+		result.State.Path = "state/state.json"
+	}
+	if result.State.ResumeWindow == 0 {
+		result.State.ResumeWindow = 15
+	}
+	if result.State.HistoryPath == "" {
+		result.State.HistoryPath = "state/history.jsonl"
+	}
+	if result.Evidence.Dir == "" {
+		result.Evidence.Dir = "evidence"
+	}
+	if result.Evidence.Retention == 0 {
+		result.Evidence.Retention = 14
+	}
+	if result.Evidence.MaxSize == 0 {
+		result.Evidence.MaxSize = 1024
+	}
+	if result.Messages.Locale == "" {
+		result.Messages.Locale = "en"
+	}
+	if result.Categories.Default == "" {
+		result.Categories.Default = "analyze"
+	}
+	if result.Categories.PollInterval == 0 {
+		result.Categories.PollInterval = 60
+	}
+	for i := range result.Categories.Rules {
+		rule := &result.Categories.Rules[i]
+		if rule.Action == "" {
+			rule.Action = "analyze"
+		}
+		if rule.Streak == "" {
+			rule.Streak = "keep"
+			if rule.Action == "ignore" {
+				rule.Streak = "freeze"
+			}
+		}
+	}
+	if result.Streamer != "" && len(result.Streamers) == 0 {
+		result.Streamers = []Streamer{{Username: result.Streamer}}
+	}
+	for i := range result.Streamers {
+		streamer := &result.Streamers[i]
+		streamer.Username = strings.ToLower(streamer.Username)
+		if streamer.DisableNotifications == nil {
+			disableNotifications := result.Twitch.DisableNotifications
+			streamer.DisableNotifications = &disableNotifications
+		}
+		if streamer.MinStreakLength == nil {
+			minStreakLength := result.Twitch.MinStreakLength
+			streamer.MinStreakLength = &minStreakLength
+		}
+		if streamer.MuteDuration == nil {
+			muteDuration := result.Twitch.MuteDuration
+			streamer.MuteDuration = &muteDuration
+		}
+		if streamer.Timezone == "" {
+			streamer.Timezone = "UTC"
+		}
+	}
 
 	validate := validator.New(validator.WithRequiredStructEnabled())
 	if err := validate.Struct(result); err != nil {
@@ -0,0 +1,27 @@
+package command
+
+import (
+	"strings"
+)
+
+type command struct {
+	name string
+	args []string
+}
+
+// parse extracts the command from a chat message like "!nm off 2h", ok is false for other messages
+func parse(text string) (command, bool) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 || !strings.EqualFold(fields[0], prefix) {
+		return command{}, false
+	}
+
+	if len(fields) == 1 {
+		return command{name: "status"}, true
+	}
+
+	return command{
+		name: strings.ToLower(fields[1]),
+		args: fields[2:],
+	}, true
+}
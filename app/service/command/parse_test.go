@@ -0,0 +1,30 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected command
+		ok       bool
+	}{
+		{text: "!nm off 2h", expected: command{name: "off", args: []string{"2h"}}, ok: true},
+		{text: "!NM  ON", expected: command{name: "on", args: []string{}}, ok: true},
+		{text: "!nm", expected: command{name: "status"}, ok: true},
+		{text: "!nmstreak", ok: false},
+		{text: "hello !nm off", ok: false},
+		{text: "", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			cmd, ok := parse(tt.text)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.expected, cmd)
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"nicemaxxingbot/app/client/twitch"
+	"nicemaxxingbot/app/client/twitch_chat"
+	"nicemaxxingbot/app/config"
+	"nicemaxxingbot/app/service/message"
+	"nicemaxxingbot/app/service/stream"
+	"nicemaxxingbot/app/util/spoken"
+	"strings"
+	"time"
+
+	"github.com/elliotchance/pie/v2"
+	"github.com/samber/do"
+)
+
+const prefix = "!nm"
+
+// replyError is a command failure explained in the chat by a message
+type replyError struct {
+	key  string
+	data message.Data
+}
+
+func (e *replyError) Error() string {
+	return fmt.Sprintf("command failed: %s %s", e.key, e.data.Arg)
+}
+
+var errUsage = &replyError{key: message.Usage}
+
+// Service executes !nm chat commands of moderators and broadcasters
+type Service struct {
+	cfg           *config.Config
+	chatClient    *twitch_chat.Client
+	twitchClient  *twitch.Client
+	streamService *stream.Service
+	messages      *message.Templates
+}
+
+func New(di *do.Injector) (*Service, error) {
+	return &Service{
+		cfg:           do.MustInvoke[*config.Config](di),
+		chatClient:    do.MustInvoke[*twitch_chat.Client](di),
+		twitchClient:  do.MustInvoke[*twitch.Client](di),
+		streamService: do.MustInvoke[*stream.Service](di),
+		messages:      do.MustInvoke[*message.Templates](di),
+	}, nil
+}
+
+func (s *Service) Run(ctx context.Context) {
+	channels := pie.Map(s.cfg.Streamers, func(streamer config.Streamer) string {
+		return streamer.Username
+	})
+
+	s.chatClient.Run(ctx, channels, func(msg twitch_chat.Message) {
+		s.handleMessage(ctx, msg)
+	})
+}
+
+func (s *Service) handleMessage(ctx context.Context, msg twitch_chat.Message) {
+	cmd, ok := parse(msg.Text)
+	if !ok || !msg.Privileged {
+		return
+	}
+
+	slogger := slog.With(
+		slog.String("channel", msg.Channel),
+		slog.String("user", msg.User),
+		slog.String("command", msg.Text),
+	)
+	slogger.Info("Executing chat command",
+		slog.Bool("telegram", true),
+	)
+
+	key, data, err := s.execute(ctx, msg.Channel, cmd)
+
+	var replyErr *replyError
+
+	switch {
+	case errors.As(err, &replyErr):
+		slogger.Warn("Chat command failed",
+			slog.Any("error", err),
+		)
+		key, data = replyErr.key, replyErr.data
+	case err != nil:
+		// the error may contain internal details, so chat only gets a generic reply
+		slogger.Error("Chat command failed",
+			slog.Any("error", err),
+			slog.Bool("telegram", true),
+		)
+		key, data = message.CommandFailed, message.Data{}
+	}
+
+	reply, err := s.messages.Render(msg.Channel, key, data)
+	if err != nil {
+		slogger.Error("Failed to render command reply",
+			slog.Any("error", err),
+		)
+		return
+	}
+
+	if err = s.twitchClient.SendMessage(msg.Channel, reply); err != nil {
+		slogger.Error("Failed to send command reply",
+			slog.Any("error", err),
+		)
+	}
+}
+
+// execute runs the command and returns the reply message
+func (s *Service) execute(ctx context.Context, channel string, cmd command) (string, message.Data, error) {
+	switch cmd.name {
+	case "off":
+		// zero means the default duration of the channel
+		var duration time.Duration
+		if len(cmd.args) > 0 {
+			arg := strings.Join(cmd.args, " ")
+
+			parsed, ok := spoken.ParseDuration(arg)
+			if !ok {
+				return "", message.Data{}, &replyError{key: message.InvalidDuration, data: message.Data{Arg: arg}}
+			}
+			duration = parsed
+		}
+
+		duration, err := s.streamService.Mute(ctx, channel, duration)
+		if err != nil {
+			return "", message.Data{}, err
+		}
+
+		return message.Muted, message.Data{MuteDuration: duration}, nil
+
+	case "on":
+		if err := s.streamService.Unmute(ctx, channel); err != nil {
+			return "", message.Data{}, err
+		}
+
+		return message.Unmuted, message.Data{}, nil
+
+	case "streak", "record", "status":
+		status, err := s.streamService.Status(channel)
+		if err != nil {
+			return "", message.Data{}, err
+		}
+
+		data := message.Data{
+			Streak:       status.Streak,
+			Record:       status.Record,
+			StreamRecord: status.StreamRecord,
+			MutedUntil:   status.MutedUntil,
+			QuietUntil:   status.QuietUntil,
+			Live:         status.Live,
+		}
+
+		switch {
+		case cmd.name == "streak":
+			return message.Streak, data, nil
+		case cmd.name == "status":
+			return message.Status, data, nil
+		case status.Record == nil:
+			return message.NoRecord, data, nil
+		default:
+			return message.Record, data, nil
+		}
+
+	case "reset":
+		if err := s.streamService.ResetStreak(ctx, channel); err != nil {
+			return "", message.Data{}, err
+		}
+
+		return message.Reset, message.Data{}, nil
+
+	default:
+		return "", message.Data{}, errUsage
+	}
+}
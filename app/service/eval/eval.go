@@ -0,0 +1,224 @@
+package eval
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"nicemaxxingbot/app/client/openai"
+)
+
+// Verdicts is the order of verdicts in reports
+var Verdicts = []openai.Verdict{openai.VerdictOK, openai.VerdictToxic, openai.VerdictOff, openai.VerdictOn}
+
+// verdictError is the actual verdict of cases the classifier failed on
+const verdictError openai.Verdict = "ERROR"
+
+// Case is a single labeled line of the evaluation corpus
+type Case struct {
+	Text    string         `json:"text"`
+	Verdict openai.Verdict `json:"verdict"`
+	Phrase  string         `json:"phrase"`
+}
+
+type Classifier func(ctx context.Context, text string) (*openai.AnalyzeResult, error)
+
+type Result struct {
+	Case   Case
+	Actual *openai.AnalyzeResult
+	Err    error
+}
+
+func (r *Result) ActualVerdict() openai.Verdict {
+	if r.Err != nil || r.Actual == nil {
+		return verdictError
+	}
+
+	return r.Actual.Verdict
+}
+
+const (
+	// share of the expected phrase words the quoted phrase must contain
+	minPhraseRecall = 0.8
+	// share of the quoted phrase words that must be in the expected phrase
+	minPhrasePrecision = 0.5
+)
+
+// PhraseMatches checks whether the quoted phrase is the expected one, ignoring case,
+// punctuation and the model selecting slightly more or less text around it
+func (r *Result) PhraseMatches() bool {
+	if r.Case.Verdict != openai.VerdictToxic || r.ActualVerdict() != openai.VerdictToxic {
+		return true
+	}
+
+	expected := strings.Fields(normalizePhrase(r.Case.Phrase))
+	actual := strings.Fields(normalizePhrase(r.Actual.Phrase))
+	if len(expected) == 0 || len(actual) == 0 {
+		return len(expected) == len(actual)
+	}
+
+	common := commonWords(expected, actual)
+
+	return float64(common)/float64(len(expected)) >= minPhraseRecall &&
+		float64(common)/float64(len(actual)) >= minPhrasePrecision
+}
+
+// commonWords counts the words of a that are also in b, repeated words are matched at most as often as they appear in b
+func commonWords(a, b []string) int {
+	counts := make(map[string]int, len(b))
+	for _, word := range b {
+		counts[word]++
+	}
+
+	common := 0
+	for _, word := range a {
+		if counts[word] > 0 {
+			counts[word]--
+			common++
+		}
+	}
+
+	return common
+}
+
+func (r *Result) Passed() bool {
+	return r.ActualVerdict() == r.Case.Verdict && r.PhraseMatches()
+}
+
+type Report struct {
+	// PromptHash identifies the evaluated classifier prompt
+	PromptHash string
+	Results    []Result
+	// Confusion counts cases by expected and actual verdict
+	Confusion map[openai.Verdict]map[openai.Verdict]int
+}
+
+func LoadCases(path string) ([]Case, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open corpus: %w", err)
+	}
+	defer file.Close()
+
+	var cases []Case
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var c Case
+		if err = json.Unmarshal([]byte(line), &c); err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+
+		c.Verdict = openai.Verdict(strings.ToUpper(string(c.Verdict)))
+		if c.Verdict == "" {
+			c.Verdict = openai.VerdictOK
+		}
+		if !slices.Contains(Verdicts, c.Verdict) {
+			return nil, fmt.Errorf("line %d: unknown verdict %q", lineNumber, c.Verdict)
+		}
+
+		cases = append(cases, c)
+	}
+
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read corpus: %w", err)
+	}
+
+	return cases, nil
+}
+
+// Run classifies every case, waiting delay between calls to stay within provider rate limits
+func Run(ctx context.Context, cases []Case, classify Classifier, delay time.Duration) *Report {
+	report := &Report{
+		Confusion: make(map[openai.Verdict]map[openai.Verdict]int),
+	}
+
+	for i, c := range cases {
+		if i > 0 && delay > 0 {
+			select {
+			case <-ctx.Done():
+				return report
+			case <-time.After(delay):
+			}
+		}
+
+		actual, err := classify(ctx, c.Text)
+		result := Result{
+			Case:   c,
+			Actual: actual,
+			Err:    err,
+		}
+
+		report.Results = append(report.Results, result)
+
+		if report.Confusion[c.Verdict] == nil {
+			report.Confusion[c.Verdict] = make(map[openai.Verdict]int)
+		}
+		report.Confusion[c.Verdict][result.ActualVerdict()]++
+	}
+
+	return report
+}
+
+// Precision of the verdict: which share of cases classified as verdict were labeled so
+func (r *Report) Precision(verdict openai.Verdict) float64 {
+	truePositive := r.Confusion[verdict][verdict]
+	predicted := 0
+
+	for _, row := range r.Confusion {
+		predicted += row[verdict]
+	}
+
+	if predicted == 0 {
+		return 0
+	}
+
+	return float64(truePositive) / float64(predicted)
+}
+
+// Recall of the verdict: which share of cases labeled as verdict were classified so
+func (r *Report) Recall(verdict openai.Verdict) float64 {
+	truePositive := r.Confusion[verdict][verdict]
+	expected := 0
+
+	for _, count := range r.Confusion[verdict] {
+		expected += count
+	}
+
+	if expected == 0 {
+		return 0
+	}
+
+	return float64(truePositive) / float64(expected)
+}
+
+func (r *Report) Passed() int {
+	passed := 0
+
+	for i := range r.Results {
+		if r.Results[i].Passed() {
+			passed++
+		}
+	}
+
+	return passed
+}
+
+func normalizePhrase(phrase string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(phrase), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}), " ")
+}
@@ -0,0 +1,80 @@
+package eval
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"nicemaxxingbot/app/client/openai"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun_Metrics(t *testing.T) {
+	answers := map[string]*openai.AnalyzeResult{
+		"toxic hit":   {Verdict: openai.VerdictToxic, Phrase: "Blight players are NOT human!"},
+		"toxic miss":  {Verdict: openai.VerdictOK},
+		"false alarm": {Verdict: openai.VerdictToxic, Phrase: "hello"},
+		"ok":          {Verdict: openai.VerdictOK},
+		"wrong quote": {Verdict: openai.VerdictToxic, Phrase: "something else"},
+	}
+
+	cases := []Case{
+		{Text: "toxic hit", Verdict: openai.VerdictToxic, Phrase: "Blight players are not human"},
+		{Text: "toxic miss", Verdict: openai.VerdictToxic, Phrase: "Eat shit"},
+		{Text: "false alarm", Verdict: openai.VerdictOK},
+		{Text: "ok", Verdict: openai.VerdictOK},
+		{Text: "wrong quote", Verdict: openai.VerdictToxic, Phrase: "You are such a loser"},
+		{Text: "broken", Verdict: openai.VerdictOff},
+	}
+
+	report := Run(context.Background(), cases, func(_ context.Context, text string) (*openai.AnalyzeResult, error) {
+		if res, ok := answers[text]; ok {
+			return res, nil
+		}
+		return nil, errors.New("boom")
+	}, 0)
+
+	assert.Equal(t, 2, report.Passed())
+	assert.Equal(t, 2, report.Confusion[openai.VerdictToxic][openai.VerdictToxic])
+	assert.Equal(t, 1, report.Confusion[openai.VerdictToxic][openai.VerdictOK])
+	assert.Equal(t, 1, report.Confusion[openai.VerdictOff][verdictError])
+
+	assert.InDelta(t, 2.0/3.0, report.Precision(openai.VerdictToxic), 0.001)
+	assert.InDelta(t, 2.0/3.0, report.Recall(openai.VerdictToxic), 0.001)
+	assert.InDelta(t, 0.5, report.Precision(openai.VerdictOK), 0.001)
+	assert.InDelta(t, 0.5, report.Recall(openai.VerdictOK), 0.001)
+	assert.Zero(t, report.Recall(openai.VerdictOff))
+
+	assert.False(t, report.Results[4].PhraseMatches())
+}
+
+func TestResult_PhraseMatches(t *testing.T) {
+	expected := "Blight players are not human"
+
+	tests := []struct {
+		actual string
+		want   bool
+	}{
+		{actual: "Blight players are NOT human!", want: true},
+		// slightly less or more text around the phrase
+		{actual: "players are not human", want: true},
+		{actual: "honestly, blight players are not human", want: true},
+		{actual: "", want: false},
+		{actual: "...", want: false},
+		{actual: "human", want: false},
+		{actual: "not human", want: false},
+		{actual: "blight", want: false},
+		{actual: "you know what, blight players are not human at all, I swear they are cheating every game", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.actual, func(t *testing.T) {
+			r := Result{
+				Case:   Case{Verdict: openai.VerdictToxic, Phrase: expected},
+				Actual: &openai.AnalyzeResult{Verdict: openai.VerdictToxic, Phrase: tt.actual},
+			}
+			assert.Equal(t, tt.want, r.PhraseMatches())
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package eval
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"nicemaxxingbot/app/client/openai"
+)
+
+func (r *Report) Print(w io.Writer) {
+	if r.PromptHash != "" {
+		_, _ = fmt.Fprintf(w, "Prompt %s\n", r.PromptHash)
+	}
+	_, _ = fmt.Fprintf(w, "Passed %d/%d cases\n\n", r.Passed(), len(r.Results))
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	_, _ = fmt.Fprintln(tw, "VERDICT\tPRECISION\tRECALL")
+	for _, verdict := range Verdicts {
+		_, _ = fmt.Fprintf(tw, "%s\t%.2f\t%.2f\n", verdict, r.Precision(verdict), r.Recall(verdict))
+	}
+	_ = tw.Flush()
+
+	_, _ = fmt.Fprintln(w)
+
+	columns := append(append([]openai.Verdict{}, Verdicts...), verdictError)
+
+	_, _ = fmt.Fprint(tw, "EXPECTED \\ ACTUAL")
+	for _, verdict := range columns {
+		_, _ = fmt.Fprintf(tw, "\t%s", verdict)
+	}
+	_, _ = fmt.Fprintln(tw)
+
+	for _, expected := range Verdicts {
+		_, _ = fmt.Fprint(tw, expected)
+		for _, actual := range columns {
+			_, _ = fmt.Fprintf(tw, "\t%d", r.Confusion[expected][actual])
+		}
+		_, _ = fmt.Fprintln(tw)
+	}
+	_ = tw.Flush()
+
+	for i := range r.Results {
+		result := &r.Results[i]
+		if result.Passed() {
+			continue
+		}
+
+		_, _ = fmt.Fprintf(w, "\n--- FAIL #%d: %q\n", i+1, result.Case.Text)
+		_, _ = fmt.Fprintf(w, "    expected: %s %q\n", result.Case.Verdict, result.Case.Phrase)
+
+		if result.Err != nil {
+			_, _ = fmt.Fprintf(w, "    error:    %v\n", result.Err)
+			continue
+		}
+
+		_, _ = fmt.Fprintf(w, "    actual:   %s %q (target=%s, confidence=%.2f)\n",
+			result.Actual.Verdict, result.Actual.Phrase, result.Actual.Target, result.Actual.Confidence)
+		if result.Actual.Rationale != "" {
+			_, _ = fmt.Fprintf(w, "    reason:   %s\n", result.Actual.Rationale)
+		}
+	}
+}
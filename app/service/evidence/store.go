@@ -0,0 +1,282 @@
+package evidence
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"nicemaxxingbot/app/config"
+	"nicemaxxingbot/app/util/audio"
+
+	"github.com/samber/do"
+)
+
+const (
+	recordFile      = "evidence.json"
+	cleanupInterval = time.Hour
+	dirTimeFormat   = "20060102T150405.000"
+	// how many records may wait to be written before new ones are dropped
+	queueSize = 16
+)
+
+// Record describes a detected phrase and the transcript it was found in
+type Record struct {
+	Channel    string    `json:"channel"`
+	Time       time.Time `json:"time"`
+	Verdict    string    `json:"verdict"`
+	Phrase     string    `json:"phrase"`
+	Target     string    `json:"target,omitempty"`
+	Confidence float64   `json:"confidence"`
+	Rationale  string    `json:"rationale,omitempty"`
+	// PromptHash identifies the classifier prompt the verdict was made with
+	PromptHash string `json:"prompt_hash,omitempty"`
+	// Transcript is the accumulated text sent to the LLM
+	Transcript string `json:"transcript"`
+	// StreamOffset is how far into the stream the phrase was said, if it was found in the transcript
+	StreamOffset *time.Duration `json:"stream_offset,omitempty"`
+	VODURL       string         `json:"vod_url,omitempty"`
+	// Chunks lists the saved audio files
+	Chunks []Chunk `json:"chunks"`
+}
+
+// Chunk is a piece of stream audio saved with the record
+type Chunk struct {
+	File string `json:"file"`
+	// StreamOffset is the start of the chunk into the stream
+	StreamOffset time.Duration `json:"stream_offset"`
+	// Text is the Whisper transcript of the chunk
+	Text string `json:"text"`
+	// PCM is raw 16 kHz mono s16le audio, saved as a WAV file
+	PCM []byte `json:"-"`
+}
+
+// Store keeps audio, transcripts and verdicts of detected phrases on disk,
+// so that notifications can be audited later
+type Store struct {
+	dir       string
+	retention time.Duration
+	maxSize   int64
+	queue     chan Record
+}
+
+func New(di *do.Injector) (*Store, error) {
+	cfg := do.MustInvoke[*config.Config](di)
+
+	return NewStore(
+		cfg.Evidence.Dir,
+		time.Duration(cfg.Evidence.Retention)*24*time.Hour,
+		int64(cfg.Evidence.MaxSize)*1024*1024,
+	)
+}
+
+// NewStore creates a store in dir that keeps records for retention and at most maxSize bytes
+func NewStore(dir string, retention time.Duration, maxSize int64) (*Store, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create evidence dir: %w", err)
+	}
+
+	return &Store{
+		dir:       dir,
+		retention: retention,
+		maxSize:   maxSize,
+		queue:     make(chan Record, queueSize),
+	}, nil
+}
+
+// Save writes the record with its audio and returns the record directory
+func (s *Store) Save(rec Record) (string, error) {
+	name := fmt.Sprintf("%s_%s", rec.Time.UTC().Format(dirTimeFormat), strings.ToLower(rec.Verdict))
+	recordDir := filepath.Join(s.dir, rec.Channel, name)
+
+	if err := os.MkdirAll(recordDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create record dir: %w", err)
+	}
+
+	for i := range rec.Chunks {
+		chunk := &rec.Chunks[i]
+
+		if err := os.WriteFile(filepath.Join(recordDir, chunk.File), audio.EncodeWAV(chunk.PCM), 0644); err != nil {
+			return "", fmt.Errorf("failed to write chunk audio: %w", err)
+		}
+	}
+
+	data, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("json.Marshal: %w", err)
+	}
+
+	if err = os.WriteFile(filepath.Join(recordDir, recordFile), data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write record: %w", err)
+	}
+
+	return recordDir, nil
+}
+
+// Enqueue hands the record over to Run, which saves it in the background, so that writing the audio
+// doesn't hold up the pipeline. It returns false if the queue is full and the record was dropped.
+func (s *Store) Enqueue(rec Record) bool {
+	select {
+	case s.queue <- rec:
+		return true
+	default:
+		return false
+	}
+}
+
+// Run saves queued records, removes expired records and enforces the size cap until ctx is canceled.
+// Records queued by then are still saved.
+func (s *Store) Run(ctx context.Context) {
+	ticker := time.NewTicker(cleanupInterval)
+	defer ticker.Stop()
+
+	s.cleanup()
+
+	for {
+		select {
+		case <-ctx.Done():
+			for {
+				select {
+				case rec := <-s.queue:
+					s.save(rec)
+				default:
+					return
+				}
+			}
+		case rec := <-s.queue:
+			s.save(rec)
+		case <-ticker.C:
+			s.cleanup()
+		}
+	}
+}
+
+// save saves the record and logs the result
+func (s *Store) save(rec Record) {
+	dir, err := s.Save(rec)
+	if err != nil {
+		slog.Error("Failed to save evidence",
+			slog.String("channel", rec.Channel),
+			slog.Any("error", err),
+		)
+		return
+	}
+
+	slog.Debug("Saved evidence",
+		slog.String("channel", rec.Channel),
+		slog.String("dir", dir),
+		slog.Int("chunks", len(rec.Chunks)),
+	)
+}
+
+// cleanup runs Cleanup and logs failures
+func (s *Store) cleanup() {
+	if err := s.Cleanup(); err != nil {
+		slog.Error("Failed to clean up evidence",
+			slog.Any("error", err),
+		)
+	}
+}
+
+type recordDir struct {
+	path    string
+	modTime time.Time
+	size    int64
+}
+
+// Cleanup removes records older than the retention period, then the oldest records until the store fits the size cap
+func (s *Store) Cleanup() error {
+	records, err := s.list()
+	if err != nil {
+		return err
+	}
+
+	slices.SortFunc(records, func(a, b recordDir) int {
+		return a.modTime.Compare(b.modTime)
+	})
+
+	var total int64
+	for _, rec := range records {
+		total += rec.size
+	}
+
+	expiry := time.Now().Add(-s.retention)
+	removed := 0
+
+	for _, rec := range records {
+		if !rec.modTime.Before(expiry) && total <= s.maxSize {
+			break
+		}
+
+		if err = os.RemoveAll(rec.path); err != nil {
+			return fmt.Errorf("failed to remove record: %w", err)
+		}
+
+		total -= rec.size
+		removed++
+	}
+
+	if removed > 0 {
+		slog.Info("Removed old evidence",
+			slog.Int("count", removed),
+			slog.Int64("size", total),
+		)
+	}
+
+	return nil
+}
+
+// list returns record directories of all channels
+func (s *Store) list() ([]recordDir, error) {
+	var records []recordDir
+
+	channels, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read evidence dir: %w", err)
+	}
+
+	for _, channel := range channels {
+		if !channel.IsDir() {
+			continue
+		}
+
+		entries, err := os.ReadDir(filepath.Join(s.dir, channel.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read channel dir: %w", err)
+		}
+
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				continue
+			}
+
+			rec := recordDir{path: filepath.Join(s.dir, channel.Name(), entry.Name())}
+
+			files, err := os.ReadDir(rec.path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read record dir: %w", err)
+			}
+
+			for _, file := range files {
+				info, err := file.Info()
+				if err != nil {
+					return nil, fmt.Errorf("failed to stat record file: %w", err)
+				}
+
+				rec.size += info.Size()
+				if info.ModTime().After(rec.modTime) {
+					rec.modTime = info.ModTime()
+				}
+			}
+
+			records = append(records, rec)
+		}
+	}
+
+	return records, nil
+}
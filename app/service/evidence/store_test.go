@@ -0,0 +1,108 @@
+package evidence
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"nicemaxxingbot/app/util/audio"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func saveRecord(t *testing.T, store *Store, at time.Time, chunkSize int) string {
+	t.Helper()
+
+	dir, err := store.Save(Record{
+		Channel:    "k0per1s",
+		Time:       at,
+		Verdict:    "TOXIC",
+		Phrase:     "Nurse players are not human",
+		Transcript: "Nurse players are not human",
+		Chunks: []Chunk{{
+			File: "chunk_0001.wav",
+			Text: "Nurse players are not human",
+			PCM:  make([]byte, chunkSize),
+		}},
+	})
+	require.NoError(t, err)
+
+	// the record age is taken from file times
+	require.NoError(t, filepath.WalkDir(dir, func(path string, _ os.DirEntry, err error) error {
+		require.NoError(t, err)
+		return os.Chtimes(path, at, at)
+	}))
+
+	return dir
+}
+
+func TestStore_Save(t *testing.T) {
+	store, err := NewStore(t.TempDir(), 24*time.Hour, 1024*1024)
+	require.NoError(t, err)
+
+	dir := saveRecord(t, store, time.Now(), 3200)
+
+	data, err := os.ReadFile(filepath.Join(dir, recordFile))
+	require.NoError(t, err)
+
+	var rec Record
+	require.NoError(t, json.Unmarshal(data, &rec))
+	assert.Equal(t, "TOXIC", rec.Verdict)
+	assert.Equal(t, "chunk_0001.wav", rec.Chunks[0].File)
+
+	wav, err := os.ReadFile(filepath.Join(dir, "chunk_0001.wav"))
+	require.NoError(t, err)
+
+	pcm, err := audio.DecodeWAV(wav)
+	require.NoError(t, err)
+	assert.Len(t, pcm, 3200)
+}
+
+func TestStore_CleanupRetention(t *testing.T) {
+	store, err := NewStore(t.TempDir(), 24*time.Hour, 1024*1024)
+	require.NoError(t, err)
+
+	now := time.Now()
+	expired := saveRecord(t, store, now.Add(-48*time.Hour), 100)
+	fresh := saveRecord(t, store, now.Add(-time.Hour), 100)
+
+	require.NoError(t, store.Cleanup())
+
+	assert.NoDirExists(t, expired)
+	assert.DirExists(t, fresh)
+}
+
+func TestStore_CleanupSizeCap(t *testing.T) {
+	store, err := NewStore(t.TempDir(), 24*time.Hour, 25000)
+	require.NoError(t, err)
+
+	now := time.Now()
+	oldest := saveRecord(t, store, now.Add(-3*time.Hour), 10000)
+	older := saveRecord(t, store, now.Add(-2*time.Hour), 10000)
+	newest := saveRecord(t, store, now.Add(-time.Hour), 10000)
+
+	require.NoError(t, store.Cleanup())
+
+	assert.NoDirExists(t, oldest)
+	assert.DirExists(t, older)
+	assert.DirExists(t, newest)
+}
+
+func TestStore_RunSavesQueued(t *testing.T) {
+	store, err := NewStore(t.TempDir(), 24*time.Hour, 1024*1024)
+	require.NoError(t, err)
+
+	at := time.Date(2025, 9, 1, 12, 0, 0, 0, time.UTC)
+	require.True(t, store.Enqueue(Record{Channel: "k0per1s", Time: at, Verdict: "OFF"}))
+
+	// records queued before shutdown are still saved
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	store.Run(ctx)
+
+	assert.FileExists(t, filepath.Join(store.dir, "k0per1s", at.Format(dirTimeFormat)+"_off", recordFile))
+}
@@ -0,0 +1,77 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"nicemaxxingbot/app/config"
+	"nicemaxxingbot/app/util/telemetry"
+	"time"
+
+	"github.com/samber/do"
+)
+
+// Server serves prometheus metrics and health probes
+type Server struct {
+	cfg    *config.Config
+	server *http.Server
+}
+
+func NewServer(di *do.Injector) (*Server, error) {
+	cfg := do.MustInvoke[*config.Config](di)
+	service := do.MustInvoke[*Service](di)
+	tel := do.MustInvoke[*telemetry.Telemetry](di)
+
+	mux := http.NewServeMux()
+	if tel.MetricsHandler != nil {
+		mux.Handle("GET /metrics", tel.MetricsHandler)
+	}
+	mux.HandleFunc("GET /healthz", probeHandler(service.Alive))
+	mux.HandleFunc("GET /readyz", probeHandler(service.Ready))
+
+	return &Server{
+		cfg: cfg,
+		server: &http.Server{
+			Addr:              cfg.Server.Addr,
+			Handler:           mux,
+			ReadHeaderTimeout: 10 * time.Second,
+		},
+	}, nil
+}
+
+func probeHandler(check func() error) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+
+		if err := check(); err != nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(err.Error() + "\n"))
+			return
+		}
+
+		_, _ = w.Write([]byte("ok\n"))
+	}
+}
+
+// Run serves until the context is cancelled
+func (s *Server) Run(ctx context.Context) {
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		_ = s.server.Shutdown(shutdownCtx)
+	}()
+
+	slog.Info("Starting HTTP server",
+		slog.String("addr", s.cfg.Server.Addr),
+	)
+
+	if err := s.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		slog.Error("HTTP server failed",
+			slog.Any("error", err),
+		)
+	}
+}
@@ -0,0 +1,113 @@
+package health
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"nicemaxxingbot/app/config"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/samber/do"
+)
+
+var errPending = errors.New("check pending")
+
+// Service tracks readiness of the dependencies and liveness of the channel pipelines
+type Service struct {
+	cfg *config.Config
+
+	m        sync.Mutex
+	checks   map[string]error
+	channels map[string]*channelHealth
+}
+
+type channelHealth struct {
+	live      bool
+	lastChunk time.Time
+}
+
+func New(di *do.Injector) (*Service, error) {
+	return &Service{
+		cfg:      do.MustInvoke[*config.Config](di),
+		checks:   make(map[string]error),
+		channels: make(map[string]*channelHealth),
+	}, nil
+}
+
+// Register adds a readiness check that stays pending until SetReady is called
+func (s *Service) Register(component string) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	s.checks[component] = errPending
+}
+
+// SetReady records the result of the component readiness check
+func (s *Service) SetReady(component string, err error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	s.checks[component] = err
+}
+
+// Ready returns an error if any registered check did not pass
+func (s *Service) Ready() error {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	var errs []error
+
+	for _, component := range slices.Sorted(maps.Keys(s.checks)) {
+		if err := s.checks[component]; err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", component, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// SetLive marks whether the channel stream is live and its pipeline is running
+func (s *Service) SetLive(channel string, live bool) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	s.channels[channel] = &channelHealth{
+		live:      live,
+		lastChunk: time.Now(),
+	}
+}
+
+// MarkChunk records that the channel pipeline produced a new chunk
+func (s *Service) MarkChunk(channel string) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if ch, ok := s.channels[channel]; ok {
+		ch.lastChunk = time.Now()
+	}
+}
+
+// Alive returns an error if any live channel has not produced a chunk for longer than the configured timeout
+func (s *Service) Alive() error {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	timeout := time.Duration(s.cfg.Server.ChunkTimeout) * time.Second
+
+	var errs []error
+
+	for _, channel := range slices.Sorted(maps.Keys(s.channels)) {
+		ch := s.channels[channel]
+		if !ch.live {
+			continue
+		}
+
+		if sinceLastChunk := time.Since(ch.lastChunk); sinceLastChunk > timeout {
+			errs = append(errs, fmt.Errorf("%s: no chunks for %v", channel, sinceLastChunk.Round(time.Second)))
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,56 @@
+package health
+
+import (
+	"errors"
+	"nicemaxxingbot/app/config"
+	"testing"
+	"time"
+
+	"github.com/samber/do"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestService(t *testing.T) *Service {
+	t.Helper()
+
+	di := do.New()
+	do.ProvideValue(di, &config.Config{Server: config.Server{ChunkTimeout: 60}})
+
+	service, err := New(di)
+	require.NoError(t, err)
+
+	return service
+}
+
+func TestService_Ready(t *testing.T) {
+	service := newTestService(t)
+	require.NoError(t, service.Ready())
+
+	service.Register("openai")
+	service.Register("whisper")
+	require.Error(t, service.Ready())
+
+	service.SetReady("openai", nil)
+	service.SetReady("whisper", errors.New("connection refused"))
+	assert.EqualError(t, service.Ready(), "whisper: connection refused")
+
+	service.SetReady("whisper", nil)
+	assert.NoError(t, service.Ready())
+}
+
+func TestService_Alive(t *testing.T) {
+	service := newTestService(t)
+
+	service.SetLive("k0per1s", true)
+	service.SetLive("offline", false)
+	require.NoError(t, service.Alive())
+
+	service.channels["k0per1s"].lastChunk = time.Now().Add(-2 * time.Minute)
+	service.channels["offline"].lastChunk = time.Now().Add(-time.Hour)
+	assert.ErrorContains(t, service.Alive(), "k0per1s: no chunks for")
+	assert.NotContains(t, service.Alive().Error(), "offline")
+
+	service.MarkChunk("k0per1s")
+	assert.NoError(t, service.Alive())
+}
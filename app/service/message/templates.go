@@ -0,0 +1,235 @@
+package message
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"text/template"
+	"time"
+
+	"nicemaxxingbot/app/config"
+	"nicemaxxingbot/app/service/state"
+
+	"github.com/ozgio/strutil"
+	"github.com/samber/do"
+	"gopkg.in/yaml.v3"
+)
+
+// MaxLength is the longest chat message Twitch accepts, longer messages are truncated after rendering
+const MaxLength = 400
+
+const defaultLocale = "en"
+
+const ellipsis = "..."
+
+// Message keys
+const (
+	StreakOver      = "streak_over"
+	Muted           = "muted"
+	Unmuted         = "unmuted"
+	Streak          = "streak"
+	Record          = "record"
+	NoRecord        = "no_record"
+	Status          = "status"
+	Reset           = "reset"
+	Usage           = "usage"
+	InvalidDuration = "invalid_duration"
+	CommandFailed   = "command_failed"
+)
+
+//go:embed en.yaml
+var builtinMessages []byte
+
+// Data is available to message templates
+type Data struct {
+	// Streamer is the channel username, it is always set
+	Streamer string
+	// Streak is the ended or current streak
+	Streak time.Duration
+	// Phrase is the toxic phrase that ended the streak
+	Phrase string
+	// StreamOffset is how far into the stream the phrase was said, empty if unknown
+	StreamOffset string
+	// Record is the longest streak before this one, nil if there was none
+	Record *state.StreakRecord
+	// StreamRecord is the longest streak of the broadcast before this one, nil if there was none
+	StreamRecord *state.StreakRecord
+	// NewRecord is whether the ended streak is longer than Record, false if there was no Record
+	NewRecord bool
+	// RecordGap is how much longer Record is than the ended streak, zero for a new record or if there was no Record
+	RecordGap time.Duration
+	// VODURL links the toxic moment in the VOD, empty if there is none
+	VODURL string
+	// MuteDuration is how long the bot is muted for
+	MuteDuration time.Duration
+	// MutedUntil is zero if the bot is not muted
+	MutedUntil time.Time
+	// QuietUntil is the end of the current quiet hours, zero if it is not quiet hours
+	QuietUntil time.Time
+	// Live is whether the stream is being processed
+	Live bool
+	// Arg is the invalid command argument
+	Arg string
+}
+
+// Templates renders chat messages in the locale of the channel
+type Templates struct {
+	// variants by locale and key
+	locales        map[string]map[string][]*template.Template
+	defaultLocale  string
+	channelLocales map[string]string
+	intN           func(n int) int
+}
+
+func New(di *do.Injector) (*Templates, error) {
+	return NewTemplates(do.MustInvoke[*config.Config](di), rand.IntN)
+}
+
+// NewTemplates parses the built-in and configured templates, intN picks the variant
+func NewTemplates(cfg *config.Config, intN func(n int) int) (*Templates, error) {
+	var builtin map[string][]string
+	if err := yaml.Unmarshal(builtinMessages, &builtin); err != nil {
+		return nil, fmt.Errorf("yaml.Unmarshal: %w", err)
+	}
+
+	t := &Templates{
+		locales:        make(map[string]map[string][]*template.Template),
+		defaultLocale:  cfg.Messages.Locale,
+		channelLocales: make(map[string]string),
+		intN:           intN,
+	}
+
+	if err := t.addLocale(defaultLocale, builtin); err != nil {
+		return nil, err
+	}
+
+	for locale, messages := range cfg.Messages.Locales {
+		for key := range messages {
+			if _, ok := builtin[key]; !ok {
+				return nil, fmt.Errorf("unknown message %q in locale %s", key, locale)
+			}
+		}
+
+		if err := t.addLocale(locale, messages); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, ok := t.locales[t.defaultLocale]; !ok {
+		return nil, fmt.Errorf("unknown default locale %s", t.defaultLocale)
+	}
+
+	for _, streamer := range cfg.Streamers {
+		if streamer.Locale == "" {
+			continue
+		}
+
+		if _, ok := t.locales[streamer.Locale]; !ok {
+			return nil, fmt.Errorf("unknown locale %s of channel %s", streamer.Locale, streamer.Username)
+		}
+
+		t.channelLocales[streamer.Username] = streamer.Locale
+	}
+
+	return t, nil
+}
+
+func (t *Templates) addLocale(locale string, messages map[string][]string) error {
+	parsed := t.locales[locale]
+	if parsed == nil {
+		parsed = make(map[string][]*template.Template, len(messages))
+		t.locales[locale] = parsed
+	}
+
+	for key, variants := range messages {
+		parsed[key] = nil
+
+		for i, variant := range variants {
+			tmpl, err := template.New(fmt.Sprintf("%s.%s[%d]", locale, key, i)).Funcs(funcs).Parse(variant)
+			if err != nil {
+				return fmt.Errorf("failed to parse message %s of locale %s: %w", key, locale, err)
+			}
+
+			parsed[key] = append(parsed[key], tmpl)
+		}
+	}
+
+	return nil
+}
+
+// Render renders a random variant of the message in the locale of the channel, falling back to the default locale
+// and then to the built-in messages. The result is truncated to MaxLength, keeping the VOD URL intact.
+func (t *Templates) Render(channel, key string, data Data) (string, error) {
+	variants := t.variants(channel, key)
+	if len(variants) == 0 {
+		return "", fmt.Errorf("unknown message %q", key)
+	}
+
+	data.Streamer = channel
+
+	var buf bytes.Buffer
+	if err := variants[t.intN(len(variants))].Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render message %s: %w", key, err)
+	}
+
+	return truncate(strings.TrimSpace(buf.String()), data.VODURL), nil
+}
+
+func (t *Templates) variants(channel, key string) []*template.Template {
+	locales := []string{t.channelLocales[channel], t.defaultLocale, defaultLocale}
+
+	for _, locale := range locales {
+		if variants := t.locales[locale][key]; len(variants) > 0 {
+			return variants
+		}
+	}
+
+	return nil
+}
+
+// truncate shortens the text to MaxLength, the protected part (e.g. a URL) is moved to the end instead of being cut
+func truncate(text, protected string) string {
+	if len(text) <= MaxLength {
+		return text
+	}
+
+	if protected == "" || !strings.Contains(text, protected) {
+		return strutil.Summary(text, MaxLength-len(ellipsis), ellipsis)
+	}
+
+	rest := strings.Join(strings.Fields(strings.Replace(text, protected, "", 1)), " ")
+
+	return strutil.Summary(rest, MaxLength-len(protected)-len(ellipsis)-1, ellipsis) + " " + protected
+}
+
+var funcs = template.FuncMap{
+	"minutes": func(d time.Duration) int {
+		return int(d.Minutes())
+	},
+	"duration": FormatDuration,
+	"date": func(t time.Time) string {
+		return t.UTC().Format(time.DateOnly)
+	},
+	// in the timezone of the time, e.g. the channel timezone
+	"datetime": func(t time.Time) string {
+		return t.Format("2006-01-02 15:04 MST")
+	},
+}
+
+// FormatDuration formats the duration rounded to minutes, e.g. 2h5m
+func FormatDuration(d time.Duration) string {
+	d = d.Round(time.Minute)
+	hours := int(d.Hours())
+	minutes := int(d.Minutes()) % 60
+
+	switch {
+	case hours == 0:
+		return fmt.Sprintf("%dm", minutes)
+	case minutes == 0:
+		return fmt.Sprintf("%dh", hours)
+	default:
+		return fmt.Sprintf("%dh%dm", hours, minutes)
+	}
+}
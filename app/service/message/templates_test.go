@@ -0,0 +1,171 @@
+package message
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"nicemaxxingbot/app/config"
+	"nicemaxxingbot/app/service/state"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestTemplates(t *testing.T, messages config.Messages, streamers ...config.Streamer) *Templates {
+	t.Helper()
+
+	if messages.Locale == "" {
+		messages.Locale = defaultLocale
+	}
+
+	templates, err := NewTemplates(&config.Config{Messages: messages, Streamers: streamers}, func(n int) int {
+		return n - 1
+	})
+	require.NoError(t, err)
+
+	return templates
+}
+
+func TestTemplates_Builtin(t *testing.T) {
+	templates := newTestTemplates(t, config.Messages{})
+	end := time.Date(2025, 9, 1, 12, 0, 0, 0, time.UTC)
+
+	text, err := templates.Render("k0per1s", StreakOver, Data{
+		Streak:       95 * time.Minute,
+		Phrase:       "Nurse players are not human",
+		StreamOffset: "1h02m03s",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Nicemaxxing streak is over pingus It lasted for ~95 minutes pingus Toxic phrase: Nurse players are not human (1h02m03s into the stream)", text)
+
+	text, err = templates.Render("k0per1s", StreakOver, Data{
+		Streak:    95 * time.Minute,
+		Phrase:    "eat shit",
+		Record:    &state.StreakRecord{Start: end.Add(-time.Hour), End: end},
+		NewRecord: true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Nicemaxxing streak is over pingus It lasted for ~95 minutes, new record! pingus Toxic phrase: eat shit", text)
+
+	text, err = templates.Render("k0per1s", StreakOver, Data{
+		Streak:    95 * time.Minute,
+		Phrase:    "eat shit",
+		Record:    &state.StreakRecord{Start: end.Add(-2 * time.Hour), End: end},
+		RecordGap: 25 * time.Minute,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Nicemaxxing streak is over pingus It lasted for ~95 minutes, 25m short of the record pingus Toxic phrase: eat shit", text)
+
+	text, err = templates.Render("k0per1s", Muted, Data{MuteDuration: 12 * time.Hour})
+	require.NoError(t, err)
+	assert.Equal(t, "pingus Bot is muted for 12h pingus", text)
+
+	text, err = templates.Render("k0per1s", Record, Data{Record: &state.StreakRecord{
+		Start:  end.Add(-2 * time.Hour),
+		End:    end,
+		Phrase: "eat shit",
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, "Nicemaxxing record: 2h, set on 2025-09-01, ended by: eat shit", text)
+
+	text, err = templates.Render("k0per1s", Status, Data{Streak: 5 * time.Minute, MutedUntil: end, Live: true})
+	require.NoError(t, err)
+	assert.Equal(t, "Streak: 5m | notifications: muted until 2025-09-01 12:00 UTC | stream: live", text)
+
+	moscow := time.FixedZone("MSK", 3*60*60)
+	text, err = templates.Render("k0per1s", Status, Data{QuietUntil: end.In(moscow)})
+	require.NoError(t, err)
+	assert.Equal(t, "Streak: 0m | notifications: quiet hours until 2025-09-01 15:00 MSK | stream: offline", text)
+
+	text, err = templates.Render("k0per1s", InvalidDuration, Data{Arg: "soon"})
+	require.NoError(t, err)
+	assert.Equal(t, `invalid duration "soon", use e.g. 30m or 2h`, text)
+
+	_, err = templates.Render("k0per1s", "unknown", Data{})
+	assert.Error(t, err)
+}
+
+func TestTemplates_Locales(t *testing.T) {
+	templates := newTestTemplates(t, config.Messages{
+		Locales: map[string]map[string][]string{
+			"ru": {
+				Unmuted: {"Бот снова работает", "{{.Streamer}}, бот снова работает"},
+			},
+		},
+	}, config.Streamer{Username: "k0per1s", Locale: "ru"})
+
+	// the test picks the last variant
+	text, err := templates.Render("k0per1s", Unmuted, Data{})
+	require.NoError(t, err)
+	assert.Equal(t, "k0per1s, бот снова работает", text)
+
+	// missing messages fall back to the built-in ones
+	text, err = templates.Render("k0per1s", Reset, Data{})
+	require.NoError(t, err)
+	assert.Equal(t, "Nicemaxxing streak was reset", text)
+
+	text, err = templates.Render("other", Unmuted, Data{})
+	require.NoError(t, err)
+	assert.Equal(t, "pingus Bot is back in action pingus", text)
+}
+
+func TestNewTemplates_Invalid(t *testing.T) {
+	tests := []struct {
+		name      string
+		messages  config.Messages
+		streamers []config.Streamer
+	}{
+		{
+			name:     "unknown key",
+			messages: config.Messages{Locale: "en", Locales: map[string]map[string][]string{"ru": {"streak_ovr": {"..."}}}},
+		},
+		{
+			name:     "broken template",
+			messages: config.Messages{Locale: "en", Locales: map[string]map[string][]string{"ru": {Reset: {"{{.Streak"}}}},
+		},
+		{
+			name:     "unknown default locale",
+			messages: config.Messages{Locale: "de"},
+		},
+		{
+			name:      "unknown channel locale",
+			messages:  config.Messages{Locale: "en"},
+			streamers: []config.Streamer{{Username: "k0per1s", Locale: "de"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewTemplates(&config.Config{Messages: tt.messages, Streamers: tt.streamers}, func(int) int { return 0 })
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	vodURL := "https://www.twitch.tv/videos/2547341592?t=1h0m26s"
+	long := strings.Repeat("word ", 100)
+
+	assert.Equal(t, "short "+vodURL, truncate("short "+vodURL, vodURL))
+
+	text := truncate(long+vodURL, vodURL)
+	assert.LessOrEqual(t, len(text), MaxLength)
+	assert.True(t, strings.HasSuffix(text, "... "+vodURL))
+
+	// the URL is kept even if the template puts it in the middle
+	text = truncate(vodURL+" "+long, vodURL)
+	assert.LessOrEqual(t, len(text), MaxLength)
+	assert.True(t, strings.HasSuffix(text, " "+vodURL))
+
+	text = truncate(long, "")
+	assert.LessOrEqual(t, len(text), MaxLength)
+	assert.True(t, strings.HasSuffix(text, "..."))
+}
+
+func TestFormatDuration(t *testing.T) {
+	assert.Equal(t, "0m", FormatDuration(20*time.Second))
+	assert.Equal(t, "45m", FormatDuration(45*time.Minute))
+	assert.Equal(t, "12h", FormatDuration(12*time.Hour))
+	assert.Equal(t, "2h5m", FormatDuration(2*time.Hour+5*time.Minute+10*time.Second))
+}
@@ -0,0 +1,75 @@
+package state
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// exportEntry is a history entry in the export formats, durations are in seconds
+type exportEntry struct {
+	Channel  string    `json:"channel"`
+	StreamID string    `json:"stream_id"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	Duration int64     `json:"duration"`
+	Excluded int64     `json:"excluded"`
+	Phrase   string    `json:"phrase"`
+}
+
+func newExportEntry(entry HistoryEntry) exportEntry {
+	return exportEntry{
+		Channel:  entry.Channel,
+		StreamID: entry.StreamID,
+		Start:    entry.Start,
+		End:      entry.End,
+		Duration: int64(entry.Duration().Seconds()),
+		Excluded: int64(entry.Excluded.Seconds()),
+		Phrase:   entry.Phrase,
+	}
+}
+
+// WriteHistoryJSON writes the entries as a JSON array
+func WriteHistoryJSON(w io.Writer, entries []HistoryEntry) error {
+	result := make([]exportEntry, 0, len(entries))
+	for _, entry := range entries {
+		result = append(result, newExportEntry(entry))
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(result); err != nil {
+		return fmt.Errorf("Encode: %w", err)
+	}
+
+	return nil
+}
+
+// WriteHistoryCSV writes the entries as CSV with a header row
+func WriteHistoryCSV(w io.Writer, entries []HistoryEntry) error {
+	writer := csv.NewWriter(w)
+
+	rows := [][]string{{"channel", "stream_id", "start", "end", "duration", "excluded", "phrase"}}
+	for _, entry := range entries {
+		e := newExportEntry(entry)
+		rows = append(rows, []string{
+			e.Channel,
+			e.StreamID,
+			e.Start.Format(time.RFC3339),
+			e.End.Format(time.RFC3339),
+			strconv.FormatInt(e.Duration, 10),
+			strconv.FormatInt(e.Excluded, 10),
+			e.Phrase,
+		})
+	}
+
+	if err := writer.WriteAll(rows); err != nil {
+		return fmt.Errorf("WriteAll: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,81 @@
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+var _ Store = (*FileStore)(nil)
+
+// FileStore keeps states of all channels in a single JSON file.
+type FileStore struct {
+	path string
+
+	m      sync.Mutex
+	states map[string]ChannelState
+}
+
+func NewFileStore(path string) (*FileStore, error) {
+	s := &FileStore{
+		path:   path,
+		states: make(map[string]ChannelState),
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+
+	if err = json.Unmarshal(data, &s.states); err != nil {
+		return nil, fmt.Errorf("failed to parse state file: %w", err)
+	}
+
+	return s, nil
+}
+
+func (s *FileStore) Load(_ context.Context, channel string) (ChannelState, error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	return s.states[channel], nil
+}
+
+func (s *FileStore) Save(_ context.Context, channel string, state ChannelState) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	s.states[channel] = state
+
+	return s.flush()
+}
+
+// flush atomically rewrites the state file, so that a crash never leaves it half-written
+func (s *FileStore) flush() error {
+	data, err := json.MarshalIndent(s.states, "", "  ")
+	if err != nil {
+		return fmt.Errorf("json.MarshalIndent: %w", err)
+	}
+
+	if err = os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create state dir: %w", err)
+	}
+
+	tmpPath := s.path + ".tmp"
+	if err = os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+
+	if err = os.Rename(tmpPath, s.path); err != nil {
+		return fmt.Errorf("failed to replace state file: %w", err)
+	}
+
+	return nil
+}
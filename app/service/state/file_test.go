@@ -0,0 +1,41 @@
+package state
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileStore_Reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "state.json")
+
+	store, err := NewFileStore(path)
+	require.NoError(t, err)
+
+	empty, err := store.Load(context.Background(), "k0per1s")
+	require.NoError(t, err)
+	assert.True(t, empty.StreakStart.IsZero())
+
+	saved := ChannelState{
+		StreakStart: time.Date(2025, 9, 1, 12, 0, 0, 0, time.UTC),
+		MutedUntil:  time.Date(2025, 9, 2, 0, 0, 0, 0, time.UTC),
+		LastSeen:    time.Date(2025, 9, 1, 15, 0, 0, 0, time.UTC),
+		LastToxicEvent: &ToxicEvent{
+			Time:   time.Date(2025, 9, 1, 11, 0, 0, 0, time.UTC),
+			Phrase: "Nurse players are not human",
+			Streak: 2 * time.Hour,
+		},
+	}
+	require.NoError(t, store.Save(context.Background(), "k0per1s", saved))
+
+	reloaded, err := NewFileStore(path)
+	require.NoError(t, err)
+
+	loaded, err := reloaded.Load(context.Background(), "k0per1s")
+	require.NoError(t, err)
+	assert.Equal(t, saved, loaded)
+}
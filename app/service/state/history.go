@@ -0,0 +1,155 @@
+package state
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"nicemaxxingbot/app/config"
+
+	"github.com/samber/do"
+)
+
+// HistoryEntry is a finished streak of a channel
+type HistoryEntry struct {
+	Channel string `json:"channel"`
+	StreakRecord
+}
+
+// History keeps every finished streak.
+type History interface {
+	// Append records a finished streak of the channel
+	Append(ctx context.Context, channel string, record StreakRecord) error
+	// List returns finished streaks of the channel in the order they ended, every channel if channel is empty
+	List(ctx context.Context, channel string) ([]HistoryEntry, error)
+}
+
+var _ History = (*FileHistory)(nil)
+var _ History = (*MemoryHistory)(nil)
+
+// NewHistory creates the streak history configured in config.State
+func NewHistory(di *do.Injector) (History, error) {
+	cfg := do.MustInvoke[*config.Config](di)
+
+	switch cfg.State.Type {
+	case "memory":
+		return NewMemoryHistory(), nil
+	case "file":
+		return NewFileHistory(cfg.State.HistoryPath), nil
+	default:
+		return nil, fmt.Errorf("unknown state store type: %s", cfg.State.Type)
+	}
+}
+
+// FileHistory appends streaks to a JSON lines file, so that it never has to be rewritten
+type FileHistory struct {
+	path string
+	m    sync.Mutex
+}
+
+func NewFileHistory(path string) *FileHistory {
+	return &FileHistory{path: path}
+}
+
+func (h *FileHistory) Append(_ context.Context, channel string, record StreakRecord) error {
+	data, err := json.Marshal(HistoryEntry{Channel: channel, StreakRecord: record})
+	if err != nil {
+		return fmt.Errorf("json.Marshal: %w", err)
+	}
+
+	h.m.Lock()
+	defer h.m.Unlock()
+
+	if err = os.MkdirAll(filepath.Dir(h.path), 0755); err != nil {
+		return fmt.Errorf("failed to create history dir: %w", err)
+	}
+
+	file, err := os.OpenFile(h.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open history file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err = file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write history file: %w", err)
+	}
+
+	return nil
+}
+
+func (h *FileHistory) List(_ context.Context, channel string) ([]HistoryEntry, error) {
+	h.m.Lock()
+	defer h.m.Unlock()
+
+	file, err := os.Open(h.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history file: %w", err)
+	}
+	defer file.Close()
+
+	var entries []HistoryEntry
+
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var entry HistoryEntry
+		if err = json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("failed to parse history file line %d: %w", line, err)
+		}
+
+		if channel == "" || entry.Channel == channel {
+			entries = append(entries, entry)
+		}
+	}
+
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history file: %w", err)
+	}
+
+	return entries, nil
+}
+
+// MemoryHistory keeps streaks in memory only, they are lost on restart.
+type MemoryHistory struct {
+	m       sync.Mutex
+	entries []HistoryEntry
+}
+
+func NewMemoryHistory() *MemoryHistory {
+	return &MemoryHistory{}
+}
+
+func (h *MemoryHistory) Append(_ context.Context, channel string, record StreakRecord) error {
+	h.m.Lock()
+	defer h.m.Unlock()
+
+	h.entries = append(h.entries, HistoryEntry{Channel: channel, StreakRecord: record})
+
+	return nil
+}
+
+func (h *MemoryHistory) List(_ context.Context, channel string) ([]HistoryEntry, error) {
+	h.m.Lock()
+	defer h.m.Unlock()
+
+	var entries []HistoryEntry
+
+	for _, entry := range h.entries {
+		if channel == "" || entry.Channel == channel {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,79 @@
+package state
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileHistory_AppendList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "history.jsonl")
+	history := NewFileHistory(path)
+
+	empty, err := history.List(context.Background(), "")
+	require.NoError(t, err)
+	assert.Empty(t, empty)
+
+	first := StreakRecord{
+		Start:    time.Date(2025, 9, 1, 12, 0, 0, 0, time.UTC),
+		End:      time.Date(2025, 9, 1, 14, 0, 0, 0, time.UTC),
+		Phrase:   "Nurse players are not human",
+		StreamID: "42",
+	}
+	second := StreakRecord{
+		Start:    time.Date(2025, 9, 1, 13, 0, 0, 0, time.UTC),
+		End:      time.Date(2025, 9, 1, 13, 30, 0, 0, time.UTC),
+		Phrase:   "uninstall",
+		StreamID: "43",
+	}
+	require.NoError(t, history.Append(context.Background(), "k0per1s", first))
+	require.NoError(t, history.Append(context.Background(), "other", second))
+
+	reloaded := NewFileHistory(path)
+
+	all, err := reloaded.List(context.Background(), "")
+	require.NoError(t, err)
+	assert.Equal(t, []HistoryEntry{
+		{Channel: "k0per1s", StreakRecord: first},
+		{Channel: "other", StreakRecord: second},
+	}, all)
+
+	filtered, err := reloaded.List(context.Background(), "other")
+	require.NoError(t, err)
+	assert.Equal(t, []HistoryEntry{{Channel: "other", StreakRecord: second}}, filtered)
+}
+
+func TestWriteHistory(t *testing.T) {
+	entries := []HistoryEntry{{
+		Channel: "k0per1s",
+		StreakRecord: StreakRecord{
+			Start:    time.Date(2025, 9, 1, 12, 0, 0, 0, time.UTC),
+			End:      time.Date(2025, 9, 1, 14, 0, 0, 0, time.UTC),
+			Excluded: 30 * time.Minute,
+			Phrase:   "Nurse players, are not human",
+			StreamID: "42",
+		},
+	}}
+
+	var csv bytes.Buffer
+	require.NoError(t, WriteHistoryCSV(&csv, entries))
+	assert.Equal(t, "channel,stream_id,start,end,duration,excluded,phrase\n"+
+		"k0per1s,42,2025-09-01T12:00:00Z,2025-09-01T14:00:00Z,5400,1800,\"Nurse players, are not human\"\n", csv.String())
+
+	var json bytes.Buffer
+	require.NoError(t, WriteHistoryJSON(&json, entries))
+	assert.JSONEq(t, `[{
+		"channel": "k0per1s",
+		"stream_id": "42",
+		"start": "2025-09-01T12:00:00Z",
+		"end": "2025-09-01T14:00:00Z",
+		"duration": 5400,
+		"excluded": 1800,
+		"phrase": "Nurse players, are not human"
+	}]`, json.String())
+}
@@ -0,0 +1,36 @@
+package state
+
+import (
+	"context"
+	"sync"
+)
+
+var _ Store = (*MemoryStore)(nil)
+
+// MemoryStore keeps states in memory only, they are lost on restart.
+type MemoryStore struct {
+	m      sync.Mutex
+	states map[string]ChannelState
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		states: make(map[string]ChannelState),
+	}
+}
+
+func (s *MemoryStore) Load(_ context.Context, channel string) (ChannelState, error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	return s.states[channel], nil
+}
+
+func (s *MemoryStore) Save(_ context.Context, channel string, state ChannelState) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	s.states[channel] = state
+
+	return nil
+}
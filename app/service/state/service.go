@@ -0,0 +1,22 @@
+package state
+
+import (
+	"fmt"
+	"nicemaxxingbot/app/config"
+
+	"github.com/samber/do"
+)
+
+// New creates the state store configured in config.State
+func New(di *do.Injector) (Store, error) {
+	cfg := do.MustInvoke[*config.Config](di)
+
+	switch cfg.State.Type {
+	case "memory":
+		return NewMemoryStore(), nil
+	case "file":
+		return NewFileStore(cfg.State.Path)
+	default:
+		return nil, fmt.Errorf("unknown state store type: %s", cfg.State.Type)
+	}
+}
@@ -0,0 +1,103 @@
+package state
+
+import (
+	"context"
+	"time"
+)
+
+// ChannelState is the persistent part of a channel pipeline state.
+type ChannelState struct {
+	// StreakStart is the start of the current nicemaxxing streak
+	StreakStart time.Time `json:"streak_start"`
+	// StreakExcluded is the time since StreakStart that doesn't count towards the streak (e.g. ad breaks)
+	StreakExcluded time.Duration `json:"streak_excluded,omitempty"`
+	// StreakFrozenAt is when the streak stopped growing (e.g. the stream switched to an ignored category),
+	// zero if it is not frozen
+	StreakFrozenAt time.Time `json:"streak_frozen_at,omitzero"`
+	// MutedUntil is the time until which notifications are muted
+	MutedUntil time.Time `json:"muted_until"`
+	// LastSeen is the last time the stream was seen live and processed
+	LastSeen time.Time `json:"last_seen"`
+	// StreamID is the ID of the last broadcast the channel was seen in
+	StreamID string `json:"stream_id,omitempty"`
+	// LastToxicEvent is the last detected toxic phrase
+	LastToxicEvent *ToxicEvent `json:"last_toxic_event,omitempty"`
+	// Record is the longest streak ever ended by a toxic phrase
+	Record *StreakRecord `json:"record,omitempty"`
+	// StreamRecord is the longest streak ended by a toxic phrase during the broadcast StreamID
+	StreamRecord *StreakRecord `json:"stream_record,omitempty"`
+}
+
+// StartStreak starts a new streak at now, a frozen streak stays frozen
+func (s *ChannelState) StartStreak(now time.Time) {
+	s.StreakStart = now
+	s.StreakExcluded = 0
+	if !s.StreakFrozenAt.IsZero() {
+		s.StreakFrozenAt = now
+	}
+}
+
+// Streak returns the length of the current streak at now, zero if there is none
+func (s *ChannelState) Streak(now time.Time) time.Duration {
+	if s.StreakStart.IsZero() {
+		return 0
+	}
+
+	excluded := s.StreakExcluded
+	if !s.StreakFrozenAt.IsZero() {
+		excluded += now.Sub(s.StreakFrozenAt)
+	}
+
+	return max(0, now.Sub(s.StreakStart)-excluded)
+}
+
+// FreezeStreak stops the streak from growing until UnfreezeStreak
+func (s *ChannelState) FreezeStreak(now time.Time) {
+	if s.StreakFrozenAt.IsZero() {
+		s.StreakFrozenAt = now
+	}
+}
+
+// UnfreezeStreak excludes the time since FreezeStreak from the streak and lets it grow again
+func (s *ChannelState) UnfreezeStreak(now time.Time) {
+	if s.StreakFrozenAt.IsZero() {
+		return
+	}
+
+	if !s.StreakStart.IsZero() {
+		s.StreakExcluded += max(0, now.Sub(s.StreakFrozenAt))
+	}
+	s.StreakFrozenAt = time.Time{}
+}
+
+type StreakRecord struct {
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"`
+	Phrase string    `json:"phrase"`
+	// Excluded is the time between Start and End that didn't count towards the streak
+	Excluded time.Duration `json:"excluded,omitempty"`
+	// StreamID is the broadcast the streak ended in, empty for replays
+	StreamID string `json:"stream_id,omitempty"`
+}
+
+func (r *StreakRecord) Duration() time.Duration {
+	return r.End.Sub(r.Start) - r.Excluded
+}
+
+type ToxicEvent struct {
+	Time   time.Time     `json:"time"`
+	Phrase string        `json:"phrase"`
+	Streak time.Duration `json:"streak"`
+	// StreamOffset is how far into the stream the phrase was said, if it was found in the transcript
+	StreamOffset *time.Duration `json:"stream_offset,omitempty"`
+	// VODURL links the phrase in the VOD
+	VODURL string `json:"vod_url,omitempty"`
+}
+
+// Store persists channel states across restarts.
+type Store interface {
+	// Load returns the saved state of the channel or an empty state if nothing was saved yet
+	Load(ctx context.Context, channel string) (ChannelState, error)
+	// Save replaces the saved state of the channel
+	Save(ctx context.Context, channel string, state ChannelState) error
+}
@@ -0,0 +1,48 @@
+package state
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChannelState_Streak(t *testing.T) {
+	start := time.Date(2025, 9, 1, 12, 0, 0, 0, time.UTC)
+
+	var st ChannelState
+	assert.Zero(t, st.Streak(start))
+
+	st.StartStreak(start)
+	st.StreakExcluded = 5 * time.Minute
+	assert.Equal(t, 55*time.Minute, st.Streak(start.Add(time.Hour)))
+
+	st.StartStreak(start.Add(time.Hour))
+	assert.Zero(t, st.StreakExcluded)
+	assert.Equal(t, time.Minute, st.Streak(start.Add(time.Hour+time.Minute)))
+}
+
+func TestChannelState_FreezeStreak(t *testing.T) {
+	start := time.Date(2025, 9, 1, 12, 0, 0, 0, time.UTC)
+
+	var st ChannelState
+	st.StartStreak(start)
+
+	st.FreezeStreak(start.Add(10 * time.Minute))
+	assert.Equal(t, 10*time.Minute, st.Streak(start.Add(30*time.Minute)))
+
+	// freezing twice keeps the first freeze time
+	st.FreezeStreak(start.Add(20 * time.Minute))
+	st.UnfreezeStreak(start.Add(40 * time.Minute))
+	assert.Zero(t, st.StreakFrozenAt)
+	assert.Equal(t, 30*time.Minute, st.StreakExcluded)
+	assert.Equal(t, 20*time.Minute, st.Streak(start.Add(50*time.Minute)))
+
+	st.UnfreezeStreak(start.Add(time.Hour))
+	assert.Equal(t, 30*time.Minute, st.StreakExcluded)
+
+	// a reset streak stays frozen
+	st.FreezeStreak(start.Add(time.Hour))
+	st.StartStreak(start.Add(2 * time.Hour))
+	assert.Zero(t, st.Streak(start.Add(3*time.Hour)))
+}
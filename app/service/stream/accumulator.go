@@ -2,6 +2,7 @@ package stream
 
 import (
 	"context"
+	"nicemaxxingbot/app/util/clock"
 	"strings"
 	"sync"
 	"time"
@@ -10,6 +11,7 @@ import (
 type StringAccumulator struct {
 	maxLength    int
 	timeout      time.Duration
+	clock        clock.Clock
 	inputChan    <-chan string
 	processFunc  func(context.Context, string)
 	buffer       strings.Builder
@@ -18,7 +20,7 @@ type StringAccumulator struct {
 	wg           sync.WaitGroup
 }
 
-func NewStringAccumulator(inputChan <-chan string, maxLength int, timeout time.Duration, processFunc func(context.Context, string)) *StringAccumulator {
+func NewStringAccumulator(inputChan <-chan string, maxLength int, timeout time.Duration, clk clock.Clock, processFunc func(context.Context, string)) *StringAccumulator {
 	if processFunc == nil {
 		panic("processFunc must not be nil")
 	}
@@ -26,9 +28,10 @@ func NewStringAccumulator(inputChan <-chan string, maxLength int, timeout time.D
 	return &StringAccumulator{
 		maxLength:    maxLength,
 		timeout:      timeout,
+		clock:        clk,
 		inputChan:    inputChan,
 		processFunc:  processFunc,
-		lastCallTime: time.Now(),
+		lastCallTime: clk.Now(),
 	}
 }
 
@@ -70,7 +73,8 @@ func (sa *StringAccumulator) accumulateAndProcess(ctx context.Context, str strin
 	sa.buffer.WriteString(" ")
 	sa.buffer.WriteString(str)
 
-	if sa.buffer.Len() < sa.maxLength {
+	// the timeout is also checked on input, so that it works with a simulated clock
+	if sa.buffer.Len() < sa.maxLength && sa.clock.Now().Sub(sa.lastCallTime) < sa.timeout {
 		return
 	}
 
@@ -81,7 +85,7 @@ func (sa *StringAccumulator) processIfTimeout(ctx context.Context) {
 	sa.mutex.Lock()
 	defer sa.mutex.Unlock()
 
-	if time.Since(sa.lastCallTime) < sa.timeout || sa.buffer.Len() == 0 {
+	if sa.clock.Now().Sub(sa.lastCallTime) < sa.timeout || sa.buffer.Len() == 0 {
 		return
 	}
 
@@ -96,7 +100,7 @@ func (sa *StringAccumulator) processBuffer(ctx context.Context) {
 	content := strings.TrimSpace(sa.buffer.String())
 
 	sa.buffer.Reset()
-	sa.lastCallTime = time.Now()
+	sa.lastCallTime = sa.clock.Now()
 
 	if content != "" {
 		sa.processFunc(ctx, content)
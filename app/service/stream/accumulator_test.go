@@ -0,0 +1,38 @@
+package stream
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"nicemaxxingbot/app/util/clock"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStringAccumulator_SimulatedTimeout(t *testing.T) {
+	start := time.Date(2025, 9, 1, 12, 0, 0, 0, time.UTC)
+	clk := clock.NewSimulated(start)
+	inputChan := make(chan string)
+
+	var m sync.Mutex
+	var batches []string
+
+	sa := NewStringAccumulator(inputChan, 1000, 2*time.Minute, clk, func(_ context.Context, text string) {
+		m.Lock()
+		defer m.Unlock()
+		batches = append(batches, text)
+	})
+	sa.Start(context.Background())
+
+	for i, text := range []string{"one", "two", "three", "four", "five"} {
+		clk.AdvanceTo(start.Add(time.Duration(i+1) * 30 * time.Second))
+		inputChan <- text
+	}
+
+	close(inputChan)
+	sa.Shutdown()
+
+	assert.Equal(t, []string{"one two three four", "five"}, batches)
+}
@@ -0,0 +1,170 @@
+package stream
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"nicemaxxingbot/app/config"
+	"nicemaxxingbot/app/service/state"
+	"nicemaxxingbot/app/util/prompt"
+)
+
+const (
+	categoryAnalyze = "analyze"
+	categoryIgnore  = "ignore"
+
+	streakKeep   = "keep"
+	streakFreeze = "freeze"
+	streakReset  = "reset"
+)
+
+// categoryRule is how speech is judged while the stream is in a category
+type categoryRule struct {
+	action string
+	// classifier prompt template, nil for the channel one
+	prompt *prompt.Template
+	streak string
+}
+
+func (r categoryRule) ignored() bool {
+	return r.action == categoryIgnore
+}
+
+// categoryRules matches Twitch categories to rules
+type categoryRules struct {
+	// keyed by the lowercase category name
+	rules    map[string]categoryRule
+	fallback categoryRule
+}
+
+// loadCategoryRules builds the rules from the config and reads the prompt files
+func loadCategoryRules(cfg config.Categories) (*categoryRules, error) {
+	r := &categoryRules{
+		rules: make(map[string]categoryRule, len(cfg.Rules)),
+		fallback: categoryRule{
+			action: cfg.Default,
+			streak: streakKeep,
+		},
+	}
+	if r.fallback.ignored() {
+		r.fallback.streak = streakFreeze
+	}
+
+	for _, rule := range cfg.Rules {
+		tmpl, err := prompt.Load("category "+rule.Name, rule.Prompt, "")
+		if err != nil {
+			return nil, fmt.Errorf("failed to load prompt of category %q: %w", rule.Name, err)
+		}
+
+		r.rules[strings.ToLower(rule.Name)] = categoryRule{
+			action: rule.Action,
+			prompt: tmpl,
+			streak: rule.Streak,
+		}
+	}
+
+	return r, nil
+}
+
+func (r *categoryRules) match(category string) categoryRule {
+	if rule, ok := r.rules[strings.ToLower(category)]; ok {
+		return rule
+	}
+
+	return r.fallback
+}
+
+// activeCategory is the category the live stream is currently in
+type activeCategory struct {
+	name string
+	rule categoryRule
+}
+
+// categoryRule returns the rule of the current category, replays and channels without a known category
+// are analyzed with the built-in prompt
+func (c *channel) categoryRule() categoryRule {
+	if active := c.category.Load(); active != nil {
+		return active.rule
+	}
+
+	return categoryRule{action: categoryAnalyze, streak: streakKeep}
+}
+
+// setCategory switches the channel to the category and applies the streak policy of its rule
+func (c *channel) setCategory(ctx context.Context, name string) {
+	prev := c.category.Load()
+	if prev != nil && prev.name == name {
+		return
+	}
+
+	rule := c.s.categories.match(name)
+	c.category.Store(&activeCategory{name: name, rule: rule})
+
+	var from string
+	if prev != nil {
+		from = prev.name
+	}
+
+	c.logger.Info("Category changed",
+		slog.String("from", from),
+		slog.String("to", name),
+		slog.String("action", rule.action),
+		slog.String("streak", rule.streak),
+		slog.Bool("telegram", true),
+	)
+
+	c.updateState(ctx, func(st *state.ChannelState) {
+		now := c.s.clock.Now()
+		st.UnfreezeStreak(now)
+
+		switch rule.streak {
+		case streakFreeze:
+			st.FreezeStreak(now)
+		case streakReset:
+			// the streak was just started or resumed when the stream went live
+			if prev != nil {
+				st.StartStreak(now)
+			}
+		}
+	})
+}
+
+// clearCategory forgets the category once the stream is offline, a frozen streak grows again
+func (c *channel) clearCategory(ctx context.Context) {
+	if c.category.Swap(nil) == nil {
+		return
+	}
+
+	c.updateState(ctx, func(st *state.ChannelState) {
+		st.UnfreezeStreak(c.s.clock.Now())
+	})
+}
+
+// watchCategory polls the channel category until ctx is canceled
+func (c *channel) watchCategory(ctx context.Context, initial string) {
+	c.setCategory(ctx, initial)
+
+	ticker := time.NewTicker(time.Duration(c.s.cfg.Categories.PollInterval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		info, err := c.s.twitchClient.GetChannelInfo(c.username)
+		if err != nil {
+			c.logger.Warn("Failed to get channel category",
+				slog.Any("error", err),
+			)
+			continue
+		}
+
+		c.setCategory(ctx, info.GameName)
+	}
+}
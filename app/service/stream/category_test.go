@@ -0,0 +1,53 @@
+package stream
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"nicemaxxingbot/app/config"
+	"nicemaxxingbot/app/util/prompt"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCategoryRules_Match(t *testing.T) {
+	promptPath := filepath.Join(t.TempDir(), "dbd.txt")
+	require.NoError(t, os.WriteFile(promptPath, []byte("judge {{.Game}} talk"), 0o644))
+
+	rules, err := loadCategoryRules(config.Categories{
+		Default: categoryIgnore,
+		Rules: []config.CategoryRule{
+			{Name: "Dead by Daylight", Action: categoryAnalyze, Prompt: config.Prompt{File: promptPath}, Streak: streakKeep},
+			{Name: "Just Chatting", Action: categoryIgnore, Streak: streakReset},
+		},
+	})
+	require.NoError(t, err)
+
+	dbd := rules.match("dead by daylight")
+	assert.False(t, dbd.ignored())
+	require.NotNil(t, dbd.prompt)
+	rendered, err := dbd.prompt.Render(prompt.Vars{Game: "Dead by Daylight"})
+	require.NoError(t, err)
+	assert.Equal(t, "judge Dead by Daylight talk", rendered.Text)
+
+	chatting := rules.match("Just Chatting")
+	assert.True(t, chatting.ignored())
+	assert.Equal(t, streakReset, chatting.streak)
+	assert.Nil(t, chatting.prompt)
+
+	other := rules.match("Minecraft")
+	assert.True(t, other.ignored())
+	assert.Equal(t, streakFreeze, other.streak)
+}
+
+func TestCategoryRules_MissingPrompt(t *testing.T) {
+	_, err := loadCategoryRules(config.Categories{
+		Default: categoryAnalyze,
+		Rules: []config.CategoryRule{
+			{Name: "Dead by Daylight", Action: categoryAnalyze, Prompt: config.Prompt{File: filepath.Join(t.TempDir(), "missing.txt")}},
+		},
+	})
+	assert.Error(t, err)
+}
@@ -0,0 +1,581 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"nicemaxxingbot/app/client/openai"
+	"nicemaxxingbot/app/client/twitch"
+	"nicemaxxingbot/app/client/twitch_live"
+	"nicemaxxingbot/app/config"
+	"nicemaxxingbot/app/service/message"
+	"nicemaxxingbot/app/service/state"
+	"nicemaxxingbot/app/util/prompt"
+	"nicemaxxingbot/app/util/spoken"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/elliotchance/pie/v2"
+	"go.opentelemetry.io/otel/attribute"
+	otelmetric "go.opentelemetry.io/otel/metric"
+)
+
+// how often LastSeen is persisted while chunks keep coming, well within the resume window
+const lastSeenSaveInterval = time.Minute
+
+// channel is a processing pipeline of a single streamer with its own ffmpeg process,
+// accumulator and streak/mute state.
+type channel struct {
+	s *Service
+
+	username             string
+	minStreakLength      int
+	disableNotifications bool
+	dataDir              string
+	logger               *slog.Logger
+	metricAttrs          otelmetric.MeasurementOption
+	// classifier prompt template of the channel
+	prompt         *prompt.Template
+	language       string
+	allowedPhrases []string
+	// default mute length
+	muteDuration time.Duration
+	location     *time.Location
+	quiet        quietHours
+
+	// number of ffmpeg starts, only accessed by the channel worker
+	ffmpegStarts int
+	// whether the pipeline is currently running
+	live atomic.Bool
+	// category of the live stream, nil if unknown
+	category atomic.Pointer[activeCategory]
+	// the live stream, nil if offline or replaying
+	stream atomic.Pointer[twitch.StreamInfo]
+	// hash of the last classifier prompt, only accessed by the accumulator
+	lastPromptHash string
+	// whether quiet hours were on at the last check
+	inQuietHours atomic.Bool
+
+	// recently transcribed words, to find when a toxic phrase was said
+	timeline timeline
+	// recently transcribed chunks, kept as evidence of detected phrases
+	recent recentChunks
+
+	m     sync.Mutex
+	state state.ChannelState
+	// when the state was last persisted
+	stateSavedAt time.Time
+}
+
+func newChannel(s *Service, streamer config.Streamer) (*channel, error) {
+	tmpl, err := prompt.Load("channel "+streamer.Username, streamer.Prompt, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load prompt of channel %s: %w", streamer.Username, err)
+	}
+	if tmpl == nil {
+		tmpl = s.classifierPrompt
+	}
+
+	location, err := time.LoadLocation(streamer.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone of channel %s: %w", streamer.Username, err)
+	}
+
+	quiet, err := parseQuietHours(location, streamer.QuietHours)
+	if err != nil {
+		return nil, fmt.Errorf("invalid quiet hours of channel %s: %w", streamer.Username, err)
+	}
+
+	return &channel{
+		s:                    s,
+		username:             streamer.Username,
+		minStreakLength:      *streamer.MinStreakLength,
+		disableNotifications: *streamer.DisableNotifications,
+		dataDir:              filepath.Join(dataDir, streamer.Username),
+		logger:               slog.With(slog.String("channel", streamer.Username)),
+		metricAttrs:          otelmetric.WithAttributes(attribute.String("channel", streamer.Username)),
+		prompt:               tmpl,
+		language:             streamer.Language,
+		allowedPhrases:       streamer.AllowedPhrases,
+		muteDuration:         time.Duration(*streamer.MuteDuration) * time.Minute,
+		location:             location,
+		quiet:                quiet,
+	}, nil
+}
+
+func (c *channel) run(ctx context.Context) {
+	c.loadState(ctx)
+
+	for {
+		stream, ok := c.waitForLive(ctx)
+		if !ok {
+			return
+		}
+
+		c.watchStream(ctx, stream)
+	}
+}
+
+// processStream runs the pipeline on the audio of the live stream until it fails or stop is closed
+func (c *channel) processStream(ctx context.Context, stream twitch.StreamInfo, stop <-chan struct{}) {
+	streamQualityArr, err := c.s.twitchLiveClient.GetM3U8(ctx, c.username)
+	if err != nil {
+		c.logger.Warn("Failed to get stream URL",
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+
+	if len(streamQualityArr) == 0 {
+		c.logger.Warn("No stream URL found")
+		return
+	}
+
+	streamQualityIndex := pie.FindFirstUsing(streamQualityArr, func(q twitch_live.StreamQuality) bool {
+		return q.AudioOnly()
+	})
+	if streamQualityIndex < 0 {
+		streamQualityIndex = 0
+	}
+	streamQuality := streamQualityArr[streamQualityIndex]
+
+	c.logger.Info("Got stream URL",
+		slog.String("quality", streamQuality.Quality),
+		slog.String("resolution", streamQuality.Resolution),
+		slog.Int("bandwidth", streamQuality.Bandwidth),
+		slog.String("codecs", streamQuality.Codecs),
+		slog.String("url", streamQuality.URL),
+		slog.Bool("telegram", true),
+	)
+
+	if err = c.runPipeline(ctx, source{
+		input:     streamQuality.URL,
+		live:      true,
+		startedAt: stream.StartedAt,
+		stop:      stop,
+	}); err != nil {
+		c.logger.Error("Failed to process chunks",
+			slog.Any("error", err),
+		)
+	}
+}
+
+func (c *channel) processText(ctx context.Context, text string) {
+	slogger := c.logger.With(
+		slog.String("text", text),
+	)
+
+	rule := c.categoryRule()
+	if rule.ignored() {
+		slogger.Debug("Skipping text, the category is ignored")
+		return
+	}
+
+	tmpl := c.prompt
+	if rule.prompt != nil {
+		tmpl = rule.prompt
+	}
+
+	systemPrompt, err := tmpl.Render(c.promptVars())
+	if err != nil {
+		slogger.Error("Failed to render prompt",
+			slog.Any("error", err),
+		)
+		return
+	}
+
+	slogger = slogger.With(slog.String("promptHash", systemPrompt.Hash))
+	if systemPrompt.Hash != c.lastPromptHash {
+		c.lastPromptHash = systemPrompt.Hash
+		slogger.Info("Using new classifier prompt",
+			slog.String("prompt", systemPrompt.Text),
+		)
+	}
+
+	slogger.Info("Processing text...")
+	toxicResult, err := c.s.toxicService.ProcessTranscriptionWithPrompt(ctx, text, systemPrompt)
+	if err != nil {
+		slogger.Error("Failed to process transcription",
+			slog.Any("error", err),
+		)
+		return
+	}
+
+	slogger.Info("Got verdict",
+		slog.String("verdict", string(toxicResult.Verdict)),
+		slog.Float64("confidence", toxicResult.Confidence),
+	)
+
+	var vodURL string
+
+	if toxicResult.Verdict != openai.VerdictOK {
+		defer func() {
+			c.saveEvidence(text, toxicResult, systemPrompt.Hash, vodURL)
+		}()
+	}
+
+	if toxicResult.Verdict == openai.VerdictOff {
+		// zero means the default duration
+		requested, _ := spoken.ParseDuration(toxicResult.Phrase)
+
+		slogger.Info("Requested to turn the bot OFF",
+			slog.String("request", toxicResult.Phrase),
+			slog.Duration("requestedDuration", requested),
+			slog.Bool("telegram", true),
+		)
+
+		duration := c.mute(ctx, requested)
+
+		if !c.disableNotifications {
+			if err = c.sendMessage(message.Muted, message.Data{MuteDuration: duration}); err != nil {
+				slogger.Error("Failed to send turn off notification",
+					slog.String("phrase", toxicResult.Phrase),
+					slog.Any("error", err),
+				)
+				return
+			}
+		}
+
+		return
+	}
+
+	if toxicResult.Verdict == openai.VerdictOn {
+		slogger.Info("Requested to turn the bot ON",
+			slog.Bool("telegram", true),
+		)
+
+		c.unmute(ctx)
+
+		if !c.disableNotifications {
+			if err = c.sendMessage(message.Unmuted, message.Data{}); err != nil {
+				slogger.Error("Failed to send turn on notification",
+					slog.String("phrase", toxicResult.Phrase),
+					slog.Any("error", err),
+				)
+				return
+			}
+		}
+
+		return
+	}
+
+	if !toxicResult.Toxic() {
+		return
+	}
+
+	var savedTime, turnOffTime time.Time
+	var streakDuration time.Duration
+	var finished, prevRecord, prevStreamRecord *state.StreakRecord
+
+	now := c.s.clock.Now()
+	streamOffset, located := c.timeline.locate(toxicResult.Phrase)
+	if located {
+		slogger = slogger.With(slog.String("streamOffset", formatStreamOffset(streamOffset)))
+	}
+
+	c.updateState(ctx, func(st *state.ChannelState) {
+		savedTime = st.StreakStart
+		turnOffTime = st.MutedUntil
+		prevRecord = st.Record
+		prevStreamRecord = st.StreamRecord
+		streakDuration = st.Streak(now)
+		excluded := now.Sub(savedTime) - streakDuration
+
+		st.StartStreak(now)
+		st.LastToxicEvent = &state.ToxicEvent{
+			Time:   now,
+			Phrase: toxicResult.Phrase,
+			Streak: streakDuration,
+		}
+		if located {
+			st.LastToxicEvent.StreamOffset = &streamOffset
+		}
+
+		if savedTime.IsZero() {
+			return
+		}
+
+		finished = &state.StreakRecord{
+			Start:    savedTime,
+			End:      now,
+			Phrase:   toxicResult.Phrase,
+			Excluded: excluded,
+			StreamID: st.StreamID,
+		}
+		if st.Record == nil || streakDuration > st.Record.Duration() {
+			st.Record = finished
+		}
+		if st.StreamRecord == nil || streakDuration > st.StreamRecord.Duration() {
+			st.StreamRecord = finished
+		}
+	})
+
+	c.s.metrics.StreakLength.Record(ctx, 0, c.metricAttrs)
+
+	if finished == nil {
+		slogger.Error("No saved time found")
+		return
+	}
+
+	// the first streak of a channel has no record to beat
+	newRecord := prevRecord != nil && streakDuration > prevRecord.Duration()
+	slogger.Info("Streak finished",
+		slog.Duration("streak", streakDuration),
+		slog.Bool("newRecord", newRecord),
+	)
+
+	if err = c.s.history.Append(ctx, c.username, *finished); err != nil {
+		slogger.Error("Failed to save streak history",
+			slog.Any("error", err),
+		)
+	}
+
+	// observe-only channels still end and record streaks, only the chat message and VOD link are skipped
+	if c.disableNotifications {
+		slogger.Info("Found toxic phrase, but notifications are disabled",
+			slog.String("phrase", toxicResult.Phrase),
+			slog.Bool("telegram", true),
+		)
+		return
+	}
+
+	streakDurationMinutes := int(streakDuration.Minutes())
+
+	if streakDurationMinutes < c.minStreakLength {
+		slogger.Info("Found toxic phrase, but streak is too low",
+			slog.String("phrase", toxicResult.Phrase),
+			slog.Bool("telegram", true),
+		)
+		return
+	}
+
+	if now.Before(turnOffTime) {
+		slogger.Info("Found toxic phrase, but bot is temporarily disabled",
+			slog.String("phrase", toxicResult.Phrase),
+			slog.Time("mutedUntil", turnOffTime),
+			slog.Bool("telegram", true),
+		)
+		return
+	}
+
+	if quietUntil, quiet := c.quiet.until(now); quiet {
+		slogger.Info("Found toxic phrase, but it is quiet hours",
+			slog.String("phrase", toxicResult.Phrase),
+			slog.Time("quietUntil", quietUntil),
+			slog.Bool("telegram", true),
+		)
+		return
+	}
+
+	vodURL = c.linkInVOD(ctx, streamOffset, located)
+
+	data := message.Data{
+		Streak:       streakDuration,
+		Phrase:       toxicResult.Phrase,
+		Record:       prevRecord,
+		StreamRecord: prevStreamRecord,
+		NewRecord:    newRecord,
+	}
+	if prevRecord != nil && !newRecord {
+		data.RecordGap = prevRecord.Duration() - streakDuration
+	}
+	if located {
+		data.StreamOffset = formatStreamOffset(streamOffset)
+	}
+	if c.s.cfg.Twitch.VODLinksInChat {
+		data.VODURL = vodURL
+	}
+
+	if err = c.sendMessage(message.StreakOver, data); err != nil {
+		slogger.Error("Failed to send notification",
+			slog.String("phrase", toxicResult.Phrase),
+			slog.Any("error", err),
+		)
+		return
+	}
+
+	slogger.Info("Found toxic phrase",
+		slog.String("phrase", toxicResult.Phrase),
+		slog.String("target", string(toxicResult.Target)),
+		slog.Float64("confidence", toxicResult.Confidence),
+		slog.String("rationale", toxicResult.Rationale),
+		slog.String("vod", vodURL),
+		slog.Bool("telegram", true),
+	)
+}
+
+// sendMessage renders the chat message in the locale of the channel and sends it
+func (c *channel) sendMessage(key string, data message.Data) error {
+	text, err := c.s.messages.Render(c.username, key, data)
+	if err != nil {
+		return fmt.Errorf("Render: %w", err)
+	}
+
+	if err = c.s.notifier.SendMessage(c.username, text); err != nil {
+		return fmt.Errorf("SendMessage: %w", err)
+	}
+
+	return nil
+}
+
+// promptVars returns the prompt template variables for the current state of the stream
+func (c *channel) promptVars() prompt.Vars {
+	vars := prompt.Vars{
+		Streamer:       c.username,
+		Language:       c.language,
+		AllowedPhrases: c.allowedPhrases,
+	}
+
+	if active := c.category.Load(); active != nil {
+		vars.Game = active.name
+	}
+
+	if stream := c.stream.Load(); stream != nil {
+		if vars.Game == "" {
+			vars.Game = stream.GameName
+		}
+		if vars.Language == "" {
+			vars.Language = stream.Language
+		}
+	}
+
+	return vars
+}
+
+// linkInVOD links the moment the phrase was said at in the VOD of the broadcast and stores the URL
+// with the last toxic event. The phrase is linked instead of clipped, as Helix can only clip the last seconds
+// of a live stream, and a verdict arrives a chunk, its transcription and the batching window later.
+// It returns an empty string if VOD links are disabled, the phrase was not located or there is no VOD.
+func (c *channel) linkInVOD(ctx context.Context, streamOffset time.Duration, located bool) string {
+	if !c.s.cfg.Twitch.VODLinks || c.s.videos == nil {
+		return ""
+	}
+
+	stream := c.stream.Load()
+	if stream == nil || !located {
+		c.logger.Info("Not linking the phrase in the VOD, the moment of the phrase is unknown")
+		return ""
+	}
+
+	videoID, err := c.s.videos.GetArchiveVideoID(c.username, stream.ID)
+	if errors.Is(err, twitch.ErrNoVOD) {
+		c.logger.Warn("Not linking the phrase in the VOD, the broadcast has no VOD (are archives turned off?)",
+			slog.String("streamId", stream.ID),
+			slog.Bool("telegram", true),
+		)
+		return ""
+	}
+	if err != nil {
+		c.logger.Error("Failed to link the phrase in the VOD",
+			slog.Any("error", err),
+		)
+		return ""
+	}
+
+	vodURL := formatVODURL(videoID, streamOffset)
+
+	c.updateState(ctx, func(st *state.ChannelState) {
+		if st.LastToxicEvent != nil {
+			st.LastToxicEvent.VODURL = vodURL
+		}
+	})
+
+	return vodURL
+}
+
+// formatVODURL links the stream offset in the VOD
+func formatVODURL(videoID string, streamOffset time.Duration) string {
+	d := streamOffset.Round(time.Second)
+
+	return fmt.Sprintf("https://www.twitch.tv/videos/%s?t=%dh%dm%ds", videoID, int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60)
+}
+
+func (c *channel) loadState(ctx context.Context) {
+	st, err := c.s.stateStore.Load(ctx, c.username)
+	if err != nil {
+		c.logger.Error("Failed to load channel state",
+			slog.Any("error", err),
+		)
+		return
+	}
+
+	c.m.Lock()
+	c.state = st
+	c.m.Unlock()
+
+	c.logger.Info("Loaded channel state",
+		slog.Time("streakStart", st.StreakStart),
+		slog.Time("mutedUntil", st.MutedUntil),
+		slog.Time("lastSeen", st.LastSeen),
+	)
+}
+
+// updateState applies fn to the channel state and persists the result
+func (c *channel) updateState(ctx context.Context, fn func(st *state.ChannelState)) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	fn(&c.state)
+	c.saveState(ctx)
+}
+
+// touchLastSeen marks the stream as seen now and returns the current streak.
+// It runs for every chunk, so the state is persisted at most once per lastSeenSaveInterval.
+func (c *channel) touchLastSeen(ctx context.Context) time.Duration {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	now := c.s.clock.Now()
+	c.state.LastSeen = now
+
+	if now.Sub(c.stateSavedAt) >= lastSeenSaveInterval {
+		c.saveState(ctx)
+	}
+
+	return c.state.Streak(now)
+}
+
+// saveState persists the channel state, c.m must be held
+func (c *channel) saveState(ctx context.Context) {
+	if err := c.s.stateStore.Save(ctx, c.username, c.state); err != nil {
+		c.logger.Error("Failed to save channel state",
+			slog.Any("error", err),
+		)
+		return
+	}
+
+	c.stateSavedAt = c.s.clock.Now()
+}
+
+// startStreak continues the saved streak if it is the same broadcast (e.g. the bot was redeployed) or the stream
+// was seen recently (e.g. the streamer restarted the broadcast), otherwise starts a new one.
+// The stream ID is empty for replays.
+func (c *channel) startStreak(ctx context.Context, streamID string) {
+	resumeWindow := time.Duration(c.s.cfg.State.ResumeWindow) * time.Minute
+
+	c.updateState(ctx, func(st *state.ChannelState) {
+		now := c.s.clock.Now()
+		sameStream := streamID != "" && streamID == st.StreamID
+
+		if !st.StreakStart.IsZero() && (sameStream || now.Sub(st.LastSeen) < resumeWindow) {
+			c.logger.Info("Continuing saved streak",
+				slog.Time("streakStart", st.StreakStart),
+				slog.Time("lastSeen", st.LastSeen),
+				slog.Bool("sameStream", sameStream),
+			)
+		} else {
+			st.StartStreak(now)
+			c.logger.Info("Starting new streak")
+		}
+
+		if streamID != st.StreamID {
+			st.StreamRecord = nil
+		}
+
+		st.LastSeen = now
+		st.StreamID = streamID
+	})
+}
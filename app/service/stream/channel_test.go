@@ -0,0 +1,63 @@
+package stream
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"nicemaxxingbot/app/service/state"
+	"nicemaxxingbot/app/util/clock"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingStore counts the saves of the channel state
+type countingStore struct {
+	*state.MemoryStore
+	saves int
+}
+
+func (s *countingStore) Save(ctx context.Context, channel string, st state.ChannelState) error {
+	s.saves++
+	return s.MemoryStore.Save(ctx, channel, st)
+}
+
+func TestTouchLastSeen(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2026, 1, 1, 20, 0, 0, 0, time.UTC)
+	simulated := clock.NewSimulated(start)
+	store := &countingStore{MemoryStore: state.NewMemoryStore()}
+
+	c := &channel{
+		s:        &Service{stateStore: store, clock: simulated},
+		username: "streamer",
+		logger:   slog.Default(),
+	}
+
+	c.updateState(ctx, func(st *state.ChannelState) {
+		st.StartStreak(start)
+		st.LastSeen = start
+	})
+	require.Equal(t, 1, store.saves)
+
+	// chunks within the interval only update the state in memory
+	for i := 1; i <= 10; i++ {
+		simulated.AdvanceTo(start.Add(time.Duration(i) * 5 * time.Second))
+		assert.Equal(t, time.Duration(i)*5*time.Second, c.touchLastSeen(ctx))
+	}
+	assert.Equal(t, 1, store.saves)
+
+	saved, err := store.Load(ctx, "streamer")
+	require.NoError(t, err)
+	assert.Equal(t, start, saved.LastSeen)
+
+	simulated.AdvanceTo(start.Add(lastSeenSaveInterval))
+	c.touchLastSeen(ctx)
+	assert.Equal(t, 2, store.saves)
+
+	saved, err = store.Load(ctx, "streamer")
+	require.NoError(t, err)
+	assert.Equal(t, start.Add(lastSeenSaveInterval), saved.LastSeen)
+}
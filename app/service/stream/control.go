@@ -0,0 +1,133 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"nicemaxxingbot/app/service/state"
+)
+
+var ErrUnknownChannel = errors.New("unknown channel")
+
+// ChannelStatus is a snapshot of the channel state
+type ChannelStatus struct {
+	// Live is whether the channel pipeline is running
+	Live bool
+	// Streak is the duration of the current streak
+	Streak time.Duration
+	// MutedUntil is zero if the bot is not muted, in the channel timezone
+	MutedUntil time.Time
+	// QuietUntil is the end of the current quiet hours, zero if it is not quiet hours
+	QuietUntil time.Time
+	// Record is the longest streak, nil if there was none yet
+	Record *state.StreakRecord
+	// StreamRecord is the longest streak of the current or last broadcast, nil if there was none yet
+	StreamRecord *state.StreakRecord
+}
+
+func (s *Service) channel(username string) (*channel, error) {
+	for _, ch := range s.channels {
+		if ch.username == username {
+			return ch, nil
+		}
+	}
+
+	return nil, ErrUnknownChannel
+}
+
+// Mute disables notifications of the channel for the duration, zero means the default duration of the channel.
+// It returns the duration the channel is muted for.
+func (s *Service) Mute(ctx context.Context, username string, duration time.Duration) (time.Duration, error) {
+	ch, err := s.channel(username)
+	if err != nil {
+		return 0, err
+	}
+
+	return ch.mute(ctx, duration), nil
+}
+
+// Unmute enables notifications of the channel
+func (s *Service) Unmute(ctx context.Context, username string) error {
+	ch, err := s.channel(username)
+	if err != nil {
+		return err
+	}
+
+	ch.unmute(ctx)
+
+	return nil
+}
+
+// ResetStreak starts a new streak without a toxic phrase, it is not counted as a record
+func (s *Service) ResetStreak(ctx context.Context, username string) error {
+	ch, err := s.channel(username)
+	if err != nil {
+		return err
+	}
+
+	ch.updateState(ctx, func(st *state.ChannelState) {
+		st.StartStreak(s.clock.Now())
+	})
+	ch.logger.Info("Streak was reset",
+		slog.Bool("telegram", true),
+	)
+
+	return nil
+}
+
+func (s *Service) Status(username string) (ChannelStatus, error) {
+	ch, err := s.channel(username)
+	if err != nil {
+		return ChannelStatus{}, err
+	}
+
+	ch.m.Lock()
+	defer ch.m.Unlock()
+
+	now := s.clock.Now()
+	status := ChannelStatus{
+		Live:         ch.live.Load(),
+		Record:       ch.state.Record,
+		StreamRecord: ch.state.StreamRecord,
+	}
+
+	status.Streak = ch.state.Streak(now)
+	if now.Before(ch.state.MutedUntil) {
+		status.MutedUntil = ch.state.MutedUntil.In(ch.location)
+	}
+	if quietUntil, quiet := ch.quiet.until(now); quiet {
+		status.QuietUntil = quietUntil
+	}
+
+	return status, nil
+}
+
+// mute disables notifications for the duration, zero means the default duration.
+// Longer durations are capped, it returns the applied duration.
+func (c *channel) mute(ctx context.Context, duration time.Duration) time.Duration {
+	if duration <= 0 {
+		duration = c.muteDuration
+	}
+	duration = min(duration, time.Duration(c.s.cfg.Twitch.MaxMuteDuration)*time.Minute)
+
+	mutedUntil := c.s.clock.Now().Add(duration)
+
+	c.updateState(ctx, func(st *state.ChannelState) {
+		st.MutedUntil = mutedUntil
+	})
+	c.logger.Info("Bot is muted",
+		slog.Duration("duration", duration),
+		slog.Time("mutedUntil", mutedUntil),
+	)
+
+	return duration
+}
+
+func (c *channel) unmute(ctx context.Context) {
+	c.updateState(ctx, func(st *state.ChannelState) {
+		st.MutedUntil = time.Time{}
+	})
+	c.logger.Info("Bot is unmuted")
+}
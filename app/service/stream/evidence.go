@@ -0,0 +1,126 @@
+package stream
+
+import (
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"nicemaxxingbot/app/client/openai"
+	"nicemaxxingbot/app/service/evidence"
+	"nicemaxxingbot/app/util/audio"
+)
+
+// maxEvidenceChunks bounds the audio kept in memory per channel for evidence (~5 minutes of 30s chunks)
+const maxEvidenceChunks = 10
+
+// recentChunk is a transcribed chunk with the text it added to the accumulator
+type recentChunk struct {
+	result chunkResult
+	// text is the transcript without the words repeated from the previous chunk
+	text string
+}
+
+// recentChunks keeps the last transcribed chunks with their audio
+type recentChunks struct {
+	m     sync.Mutex
+	items []recentChunk
+}
+
+func (r *recentChunks) reset() {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	r.items = nil
+}
+
+func (r *recentChunks) add(result chunkResult, text string) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	r.items = append(r.items, recentChunk{result: result, text: text})
+	if len(r.items) > maxEvidenceChunks {
+		r.items = r.items[len(r.items)-maxEvidenceChunks:]
+	}
+}
+
+// around returns the chunk containing the stream offset and the next one, as phrases may cross chunk boundaries
+func (r *recentChunks) around(offset time.Duration) []chunkResult {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	for i, item := range r.items {
+		end := item.result.streamOffset + audio.Duration(item.result.ch.pcm)
+		if offset >= item.result.streamOffset && offset < end {
+			var results []chunkResult
+			for _, next := range r.items[i:min(i+2, len(r.items))] {
+				results = append(results, next.result)
+			}
+
+			return results
+		}
+	}
+
+	return nil
+}
+
+// inBatch returns the chunks whose text is part of the batch sent to the LLM
+func (r *recentChunks) inBatch(batch string) []chunkResult {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	var results []chunkResult
+	for _, item := range r.items {
+		if item.text != "" && strings.Contains(batch, item.text) {
+			results = append(results, item.result)
+		}
+	}
+
+	return results
+}
+
+// saveEvidence queues the audio, transcript and verdict of a detected phrase to be stored in the background
+func (c *channel) saveEvidence(text string, result *openai.AnalyzeResult, promptHash, vodURL string) {
+	if c.s.evidence == nil {
+		return
+	}
+
+	rec := evidence.Record{
+		Channel:    c.username,
+		Time:       c.s.clock.Now(),
+		Verdict:    string(result.Verdict),
+		Phrase:     result.Phrase,
+		Target:     string(result.Target),
+		Confidence: result.Confidence,
+		Rationale:  result.Rationale,
+		PromptHash: promptHash,
+		Transcript: text,
+		VODURL:     vodURL,
+	}
+
+	offset, located := c.timeline.locate(result.Phrase)
+
+	var chunks []chunkResult
+	if located {
+		rec.StreamOffset = &offset
+		chunks = c.recent.around(offset)
+	} else {
+		// OFF and ON verdicts and unlocated phrases are backed by the whole batch
+		chunks = c.recent.inBatch(text)
+	}
+
+	for _, item := range chunks {
+		rec.Chunks = append(rec.Chunks, evidence.Chunk{
+			File:         item.ch.name(),
+			StreamOffset: item.streamOffset,
+			Text:         item.transcription.Text,
+			PCM:          item.ch.pcm,
+		})
+	}
+
+	if !c.s.evidence.Enqueue(rec) {
+		c.logger.Warn("Evidence queue is full, dropping evidence",
+			slog.String("verdict", rec.Verdict),
+		)
+	}
+}
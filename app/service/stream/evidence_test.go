@@ -0,0 +1,50 @@
+package stream
+
+import (
+	"testing"
+	"time"
+
+	"nicemaxxingbot/app/util/audio"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func chunkAt(index int) chunkResult {
+	return chunkResult{
+		ch:           chunk{index: index, pcm: make([]byte, audio.Bytes(30*time.Second))},
+		streamOffset: time.Duration(index) * 30 * time.Second,
+	}
+}
+
+func TestRecentChunks_InBatch(t *testing.T) {
+	var recent recentChunks
+
+	recent.add(chunkAt(0), "welcome back chat")
+	recent.add(chunkAt(1), "let's go again")
+	recent.add(chunkAt(2), "bot off for tonight")
+	recent.add(chunkAt(3), "")
+	recent.add(chunkAt(4), "gg")
+
+	// the first two chunks went to an earlier batch
+	chunks := recent.inBatch("bot off for tonight gg")
+	if assert.Len(t, chunks, 2) {
+		assert.Equal(t, 2, chunks[0].ch.index)
+		assert.Equal(t, 4, chunks[1].ch.index)
+	}
+}
+
+func TestRecentChunks_Around(t *testing.T) {
+	var recent recentChunks
+
+	for i := range 3 {
+		recent.add(chunkAt(i), "text")
+	}
+
+	chunks := recent.around(45 * time.Second)
+	if assert.Len(t, chunks, 2) {
+		assert.Equal(t, 1, chunks[0].ch.index)
+		assert.Equal(t, 2, chunks[1].ch.index)
+	}
+
+	assert.Empty(t, recent.around(5*time.Minute))
+}
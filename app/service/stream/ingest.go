@@ -0,0 +1,567 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"nicemaxxingbot/app/client/hls"
+	"nicemaxxingbot/app/service/state"
+	"nicemaxxingbot/app/util/audio"
+)
+
+// source is the media the pipeline reads
+type source struct {
+	// ffmpeg input: a stream URL or a local file path
+	input string
+	// live sources are network streams, local files are read as fast as possible
+	live bool
+	// when the stream went live, zero if the media starts with the pipeline
+	startedAt time.Time
+	// closed when the stream went offline, the pipeline then finishes the chunks it has and returns
+	stop <-chan struct{}
+}
+
+func (src source) inputArgs() []string {
+	if src.live {
+		return []string{
+			"-hwaccel", "auto",
+			"-timeout", "10000000", // 10s
+			"-reconnect", "0",
+			"-reconnect_at_eof", "0",
+			"-reconnect_streamed", "0",
+			"-reconnect_delay_max", "0",
+			"-i", src.input,
+		}
+	}
+
+	return []string{
+		"-i", src.input,
+		"-vn",
+	}
+}
+
+// chunk is a piece of audio cut from the source
+type chunk struct {
+	// sequence number within the pipeline run
+	index int
+	// start of the chunk on the media timeline, relative to the pipeline start
+	offset time.Duration
+	// raw 16 kHz mono s16le audio
+	pcm []byte
+}
+
+// end of the chunk on the media timeline, relative to the pipeline start
+func (ch chunk) end() time.Duration {
+	return ch.offset + audio.Duration(ch.pcm)
+}
+
+func (ch chunk) name() string {
+	return fmt.Sprintf("chunk_%04d.wav", ch.index)
+}
+
+// ingest runs ffmpeg on the source and sends the audio chunks it produces until the source ends
+func (c *channel) ingest(ctx context.Context, src source, chunks chan<- chunk) error {
+	switch {
+	case c.s.cfg.Processing.Ingest == "hls" && src.live:
+		return c.ingestHLS(ctx, src, chunks)
+	case c.s.cfg.Processing.Ingest == "hls", c.s.cfg.Processing.Ingest == "pipe":
+		// local files are not playlists, they are decoded by ffmpeg directly
+		return c.ingestPipe(ctx, src, chunks)
+	default:
+		return c.ingestFiles(ctx, src, chunks)
+	}
+}
+
+// countFFmpegStart records every ffmpeg start after the first one of the channel as a restart
+func (c *channel) countFFmpegStart(ctx context.Context) {
+	if c.ffmpegStarts > 0 {
+		c.s.metrics.FFmpegRestarts.Add(ctx, 1, c.metricAttrs)
+	}
+	c.ffmpegStarts++
+}
+
+// pcmOutputArgs makes ffmpeg write raw PCM to stdout
+var pcmOutputArgs = []string{
+	"-vn",
+	"-ac", "1",
+	"-ar", fmt.Sprint(audio.SampleRate),
+	"-f", "s16le",
+	"-c:a", "pcm_s16le",
+	"pipe:1",
+}
+
+// ingestPipe reads raw PCM from ffmpeg stdout and cuts it into chunks in memory
+func (c *channel) ingestPipe(ctx context.Context, src source, chunks chan<- chunk) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	c.countFFmpegStart(ctx)
+
+	cmd := exec.CommandContext(ctx, "ffmpeg", append(src.inputArgs(), pcmOutputArgs...)...)
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("StdoutPipe: %w", err)
+	}
+
+	if err = cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start ffmpeg: %w", err)
+	}
+
+	splitErr := c.splitPCM(ctx, stdout, chunks, &pcmPosition{})
+	if splitErr != nil {
+		// stop ffmpeg, nobody reads its output anymore
+		cancel()
+	}
+
+	waitErr := cmd.Wait()
+
+	if splitErr != nil {
+		return fmt.Errorf("split: %w", splitErr)
+	}
+	if waitErr != nil && ctx.Err() == nil {
+		c.logger.Error("FFMpeg failed",
+			slog.Any("error", waitErr),
+		)
+	}
+
+	return nil
+}
+
+// hlsSegment is a downloaded content segment on its way to the decoder
+type hlsSegment struct {
+	sequence int64
+	data     []byte
+	// the decoder is restarted before the segment, timestamps or encoding may change at a discontinuity
+	restart bool
+	// stream time before the segment that is not in the audio (ad breaks and gaps), it shifts later chunks
+	skipped time.Duration
+}
+
+// ingestHLS downloads segments of the media playlist natively and only uses ffmpeg to decode them to PCM.
+// Ad breaks are dropped and every discontinuity starts a new ffmpeg process, so that a codec or timestamp
+// reset can't corrupt or stall decoding.
+func (c *channel) ingestHLS(ctx context.Context, src source, chunks chan<- chunk) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	segments := make(chan hlsSegment)
+	followDone := make(chan error, 1)
+
+	go func() {
+		defer close(segments)
+
+		var followErr error
+		defer func() {
+			followDone <- followErr
+		}()
+		defer c.recoverPanic(cancel)
+
+		followErr = c.followHLS(ctx, src, segments)
+	}()
+
+	var pos pcmPosition
+	var err error
+
+	next, ok := <-segments
+	for ok && err == nil && ctx.Err() == nil {
+		pos.offset += next.skipped
+		next, ok, err = c.decodeHLS(ctx, next, segments, chunks, &pos)
+	}
+
+	// the decoder is done, stop following the playlist
+	cancel()
+
+	followErr := <-followDone
+
+	if err != nil {
+		return err
+	}
+	if followErr != nil && !errors.Is(followErr, context.Canceled) {
+		return fmt.Errorf("follow playlist: %w", followErr)
+	}
+
+	return nil
+}
+
+// followHLS sends the content segments of the playlist to the decoder and accounts for ad breaks and gaps
+func (c *channel) followHLS(ctx context.Context, src source, segments chan<- hlsSegment) error {
+	var adBreak, skipped time.Duration
+	// where the next segment starts on the wall clock, zero if the playlist has no program date times
+	var expected time.Time
+
+	return c.s.hlsClient.Follow(ctx, src.input, func(seg hls.Segment, data []byte) error {
+		var gap time.Duration
+		if seg.Discontinuity && !expected.IsZero() && !seg.ProgramDateTime.IsZero() {
+			gap = seg.ProgramDateTime.Sub(expected)
+		}
+		if !seg.ProgramDateTime.IsZero() {
+			expected = seg.ProgramDateTime.Add(seg.Duration)
+		}
+
+		if seg.Ad {
+			if adBreak == 0 {
+				c.logger.Info("Ad break started, skipping",
+					slog.Int64("sequence", seg.Sequence),
+				)
+			}
+
+			adBreak += seg.Duration
+			skipped += seg.Duration
+			c.s.metrics.AdSeconds.Add(ctx, seg.Duration.Seconds(), c.metricAttrs)
+			c.excludeFromStreak(ctx, seg.Duration)
+
+			return nil
+		}
+
+		if adBreak > 0 {
+			c.logger.Info("Ad break ended",
+				slog.Int64("sequence", seg.Sequence),
+				slog.Duration("duration", adBreak),
+			)
+			adBreak = 0
+		} else if seg.Discontinuity {
+			c.logger.Info("Stream discontinuity, restarting decoder",
+				slog.Int64("sequence", seg.Sequence),
+				slog.String("title", seg.Title),
+				slog.Duration("gap", gap),
+			)
+		}
+
+		if gap > 0 {
+			skipped += gap
+			c.excludeFromStreak(ctx, gap)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case segments <- hlsSegment{
+			sequence: seg.Sequence,
+			data:     data,
+			restart:  seg.Discontinuity || skipped > 0,
+			skipped:  skipped,
+		}:
+		}
+
+		skipped = 0
+
+		return nil
+	})
+}
+
+// hlsWriteResult is why the writer of a decoder run stopped
+type hlsWriteResult struct {
+	// next is the segment that needs a decoder restart, valid if restart is true
+	next    hlsSegment
+	restart bool
+	// ended is true if there are no more segments
+	ended bool
+	err   error
+}
+
+// decodeHLS decodes segments with one ffmpeg process, starting with first, until the segments end or the next one
+// needs a decoder restart. It returns that segment, ok is false if the segments ended or ctx is canceled.
+// An error is returned if ffmpeg stopped before its input ended.
+func (c *channel) decodeHLS(ctx context.Context, first hlsSegment, segments <-chan hlsSegment, chunks chan<- chunk, pos *pcmPosition) (hlsSegment, bool, error) {
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	c.countFFmpegStart(runCtx)
+
+	cmd := exec.CommandContext(runCtx, "ffmpeg", append([]string{"-i", "pipe:0"}, pcmOutputArgs...)...)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return hlsSegment{}, false, fmt.Errorf("StdinPipe: %w", err)
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return hlsSegment{}, false, fmt.Errorf("StdoutPipe: %w", err)
+	}
+
+	if err = cmd.Start(); err != nil {
+		return hlsSegment{}, false, fmt.Errorf("failed to start ffmpeg: %w", err)
+	}
+
+	writeDone := make(chan hlsWriteResult, 1)
+
+	go func() {
+		// closing stdin lets ffmpeg flush the tail of the run
+		defer stdin.Close()
+
+		var res hlsWriteResult
+		defer func() {
+			writeDone <- res
+		}()
+		defer c.recoverPanic(cancel)
+
+		seg := first
+		for {
+			if _, err := stdin.Write(seg.data); err != nil {
+				res.err = fmt.Errorf("failed to write segment %d to ffmpeg: %w", seg.sequence, err)
+				return
+			}
+
+			var ok bool
+			select {
+			case <-runCtx.Done():
+				return
+			case seg, ok = <-segments:
+			}
+
+			if !ok {
+				res.ended = true
+				return
+			}
+			if seg.restart {
+				res.next = seg
+				res.restart = true
+				return
+			}
+		}
+	}()
+
+	splitErr := c.splitPCM(runCtx, stdout, chunks, pos)
+
+	// ffmpeg is done with its output, the writer has nothing to write to
+	cancel()
+
+	res := <-writeDone
+	waitErr := cmd.Wait()
+
+	if ctx.Err() != nil {
+		return hlsSegment{}, false, nil
+	}
+	if splitErr != nil {
+		return hlsSegment{}, false, fmt.Errorf("split: %w", splitErr)
+	}
+
+	if !res.restart && !res.ended {
+		// the writer was cut off, ffmpeg closed its output while it still had input to decode
+		if waitErr == nil {
+			waitErr = errors.New("ffmpeg exited before its input ended")
+		}
+
+		return hlsSegment{}, false, fmt.Errorf("ffmpeg failed: %w", errors.Join(waitErr, res.err))
+	}
+
+	// stdin was closed, so the exit status is not caused by cancel
+	if waitErr != nil {
+		c.logger.Error("FFMpeg failed",
+			slog.Any("error", waitErr),
+		)
+	}
+
+	return res.next, res.restart, nil
+}
+
+// excludeFromStreak keeps stream time that is not in the audio (ad breaks and gaps) from counting towards the streak
+func (c *channel) excludeFromStreak(ctx context.Context, d time.Duration) {
+	c.updateState(ctx, func(st *state.ChannelState) {
+		if !st.StreakStart.IsZero() {
+			st.StreakExcluded += d
+		}
+	})
+}
+
+// pcmPosition is where the next chunk starts, it carries over decoder restarts
+type pcmPosition struct {
+	index int
+	// stream offset relative to the pipeline start, including dropped ads and gaps
+	offset time.Duration
+}
+
+// splitPCM cuts the raw PCM output of ffmpeg into chunks starting at pos until it ends
+func (c *channel) splitPCM(ctx context.Context, pcm io.Reader, chunks chan<- chunk, pos *pcmPosition) error {
+	splitter := audio.Splitter{
+		MaxLength:        time.Duration(c.s.cfg.Processing.ChunkLength) * time.Second,
+		SilenceThreshold: c.s.cfg.Processing.SilenceThreshold,
+	}
+	if c.s.cfg.Processing.SplitOnSilence {
+		splitter.MinLength = time.Duration(c.s.cfg.Processing.MinChunkLength) * time.Second
+	}
+
+	return splitter.Split(pcm, func(pcm []byte) error {
+		ch := chunk{
+			index:  pos.index,
+			offset: pos.offset,
+			pcm:    pcm,
+		}
+		pos.index++
+		pos.offset += audio.Duration(pcm)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case chunks <- ch:
+			return nil
+		}
+	})
+}
+
+// ingestFiles lets ffmpeg cut WAV segments into the data dir and reads them as soon as they are complete
+func (c *channel) ingestFiles(ctx context.Context, src source, chunks chan<- chunk) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	_ = os.RemoveAll(c.dataDir)
+
+	if err := os.MkdirAll(c.dataDir, 0755); err != nil {
+		return fmt.Errorf("failed to create data dir: %w", err)
+	}
+
+	chunkLength := time.Duration(c.s.cfg.Processing.ChunkLength) * time.Second
+
+	args := append(src.inputArgs(),
+		"-f", "segment",
+		"-segment_time", fmt.Sprint(chunkLength.Seconds()),
+		"-reset_timestamps", "1",
+		"-ac", "1",
+		"-ar", fmt.Sprint(audio.SampleRate),
+		"-c:a", "pcm_s16le",
+		filepath.Join(c.dataDir, "chunk_%04d.wav"),
+	)
+
+	c.countFFmpegStart(ctx)
+
+	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
+	ffmpegDone := make(chan struct{})
+
+	go func() {
+		defer close(ffmpegDone)
+		defer c.recoverPanic(cancel)
+
+		if err := cmd.Run(); err != nil && ctx.Err() == nil {
+			c.logger.Error("FFMpeg failed",
+				slog.Any("error", err),
+			)
+		}
+	}()
+	defer func() {
+		cancel()
+		<-ffmpegDone
+	}()
+
+	processedMap := make(map[string]struct{})
+	lastNewChunkTime := time.Now()
+	checkInterval := 5 * time.Second
+	timeoutDuration := 2 * time.Minute
+
+	for {
+		finished := false
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ffmpegDone:
+			// the last file is complete now
+			finished = true
+		default:
+		}
+
+		files, err := filepath.Glob(filepath.Join(c.dataDir, "*.wav"))
+		if err != nil {
+			return fmt.Errorf("filepath.Glob: %w", err)
+		}
+
+		slices.Sort(files)
+		fileCount := len(files)
+
+		newChunkFound := false
+		processedCount := 0
+
+		for i, file := range files {
+			// skip last file, as it might be incomplete
+			if i == fileCount-1 && !finished {
+				continue
+			}
+
+			if _, ok := processedMap[file]; ok {
+				continue
+			}
+
+			newChunkFound = true
+			processedMap[file] = struct{}{}
+			processedCount++
+
+			var index int
+			if _, err = fmt.Sscanf(filepath.Base(file), "chunk_%d.wav", &index); err != nil {
+				return fmt.Errorf("unexpected chunk name %s: %w", file, err)
+			}
+
+			pcm, err := readWAVFile(file)
+			if err != nil {
+				c.logger.Error("Failed to read chunk",
+					slog.String("file", file),
+					slog.Any("error", err),
+				)
+				continue
+			}
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case chunks <- chunk{
+				index:  index,
+				offset: time.Duration(index) * chunkLength,
+				pcm:    pcm,
+			}:
+			}
+		}
+
+		if finished {
+			return nil
+		}
+
+		if newChunkFound {
+			lastNewChunkTime = time.Now()
+			c.logger.Debug("Got new chunks",
+				slog.Int("count", processedCount),
+				slog.Time("lastNewChunkTime", lastNewChunkTime),
+			)
+		} else {
+			if time.Since(lastNewChunkTime) > timeoutDuration {
+				return fmt.Errorf("no new chunks found for %v", timeoutDuration)
+			}
+
+			c.logger.Debug("No new chunks found",
+				slog.Duration("timeSinceLastChunk", time.Since(lastNewChunkTime)),
+				slog.Duration("timeoutIn", timeoutDuration-time.Since(lastNewChunkTime)),
+			)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ffmpegDone:
+		case <-time.After(checkInterval):
+		}
+	}
+}
+
+// readWAVFile loads the PCM data of a finished segment and removes the file
+func readWAVFile(filePath string) ([]byte, error) {
+	defer os.Remove(filePath)
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("ReadFile: %w", err)
+	}
+
+	pcm, err := audio.DecodeWAV(data)
+	if err != nil {
+		return nil, fmt.Errorf("DecodeWAV: %w", err)
+	}
+
+	return pcm, nil
+}
@@ -0,0 +1,152 @@
+package stream
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"nicemaxxingbot/app/client/hls"
+	"nicemaxxingbot/app/config"
+	"nicemaxxingbot/app/service/state"
+	"nicemaxxingbot/app/util/clock"
+	"nicemaxxingbot/app/util/telemetry"
+
+	"github.com/samber/do"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFollowHLS(t *testing.T) {
+	// an ad break, then a two second gap before 4104
+	playlist := "#EXTM3U\n#EXT-X-TARGETDURATION:2\n#EXT-X-MEDIA-SEQUENCE:4100\n" +
+		"#EXT-X-PROGRAM-DATE-TIME:2025-09-01T13:23:45.000Z\n#EXTINF:2.000,live\n4100.ts\n" +
+		"#EXT-X-DISCONTINUITY\n#EXT-X-PROGRAM-DATE-TIME:2025-09-01T13:23:47.000Z\n#EXTINF:2.000,Amazon|8537520512\n4101.ts\n" +
+		"#EXT-X-DISCONTINUITY\n#EXT-X-PROGRAM-DATE-TIME:2025-09-01T13:23:49.000Z\n#EXTINF:2.000,live\n4102.ts\n" +
+		"#EXT-X-PROGRAM-DATE-TIME:2025-09-01T13:23:51.000Z\n#EXTINF:2.000,live\n4103.ts\n" +
+		"#EXT-X-DISCONTINUITY\n#EXT-X-PROGRAM-DATE-TIME:2025-09-01T13:23:55.000Z\n#EXTINF:2.000,live\n4104.ts\n" +
+		"#EXT-X-ENDLIST\n"
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/playlist.m3u8" {
+			_, _ = fmt.Fprint(w, playlist)
+			return
+		}
+		_, _ = fmt.Fprint(w, r.URL.Path)
+	}))
+	defer server.Close()
+
+	di := do.New()
+	do.ProvideValue(di, telemetry.NewNoopMetrics())
+
+	hlsClient, err := hls.NewClient(di)
+	require.NoError(t, err)
+
+	streakStart := time.Date(2025, 9, 1, 13, 0, 0, 0, time.UTC)
+	c := &channel{
+		s: &Service{
+			hlsClient:  hlsClient,
+			stateStore: state.NewMemoryStore(),
+			metrics:    telemetry.NewNoopMetrics(),
+			clock:      clock.Real{},
+		},
+		username: "k0per1s",
+		logger:   slog.Default(),
+		state:    state.ChannelState{StreakStart: streakStart},
+	}
+
+	segments := make(chan hlsSegment)
+	done := make(chan error, 1)
+
+	go func() {
+		defer close(segments)
+		done <- c.followHLS(context.Background(), source{input: server.URL + "/playlist.m3u8"}, segments)
+	}()
+
+	var got []hlsSegment
+	for seg := range segments {
+		seg.data = nil
+		got = append(got, seg)
+	}
+	require.NoError(t, <-done)
+
+	assert.Equal(t, []hlsSegment{
+		{sequence: 4100},
+		// the decoder restarts after the ad break, later chunks are shifted by it
+		{sequence: 4102, restart: true, skipped: 2 * time.Second},
+		{sequence: 4103},
+		{sequence: 4104, restart: true, skipped: 2 * time.Second},
+	}, got)
+
+	// neither the ad nor the gap count towards the streak
+	assert.Equal(t, 4*time.Second, c.state.StreakExcluded)
+}
+
+// fakeFFmpeg puts an ffmpeg script with the given body first in PATH
+func fakeFFmpeg(t *testing.T, body string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "ffmpeg"), []byte("#!/bin/sh\n"+body+"\n"), 0755))
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func newDecodeTestChannel() *channel {
+	return &channel{
+		s: &Service{
+			cfg:     &config.Config{Processing: config.Processing{ChunkLength: 30}},
+			metrics: telemetry.NewNoopMetrics(),
+		},
+		username: "k0per1s",
+		logger:   slog.Default(),
+	}
+}
+
+func TestDecodeHLS_Restart(t *testing.T) {
+	fakeFFmpeg(t, "cat > /dev/null")
+
+	segments := make(chan hlsSegment, 2)
+	segments <- hlsSegment{sequence: 4101, data: []byte("segment")}
+	segments <- hlsSegment{sequence: 4102, data: []byte("segment"), restart: true}
+
+	next, ok, err := newDecodeTestChannel().decodeHLS(context.Background(), hlsSegment{sequence: 4100, data: []byte("segment")},
+		segments, make(chan chunk), &pcmPosition{})
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, int64(4102), next.sequence)
+}
+
+func TestDecodeHLS_FFmpegDied(t *testing.T) {
+	// ffmpeg exits while the next segment is not downloaded yet
+	fakeFFmpeg(t, "head -c 1 > /dev/null; exit 1")
+
+	segments := make(chan hlsSegment)
+
+	_, ok, err := newDecodeTestChannel().decodeHLS(context.Background(), hlsSegment{sequence: 4100, data: []byte("segment")},
+		segments, make(chan chunk), &pcmPosition{})
+	require.Error(t, err)
+	assert.False(t, ok)
+	assert.Contains(t, err.Error(), "exit status 1")
+}
+
+func TestDecodeHLS_Canceled(t *testing.T) {
+	fakeFFmpeg(t, "cat > /dev/null")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	segments := make(chan hlsSegment)
+
+	go func() {
+		segments <- hlsSegment{sequence: 4101, data: []byte("segment")}
+		cancel()
+	}()
+
+	_, ok, err := newDecodeTestChannel().decodeHLS(ctx, hlsSegment{sequence: 4100, data: []byte("segment")},
+		segments, make(chan chunk), &pcmPosition{})
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
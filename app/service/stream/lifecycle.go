@@ -0,0 +1,158 @@
+package stream
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"nicemaxxingbot/app/client/twitch"
+)
+
+const (
+	// how often an offline channel is checked
+	offlinePollInterval = time.Minute
+	// how often a live channel is checked
+	onlinePollInterval = time.Minute
+	// upper bound of the poll interval when the Twitch API keeps failing
+	maxPollBackoff = 10 * time.Minute
+	// Helix sometimes reports a live channel as offline for a moment
+	offlineConfirmations = 2
+	// delay before the pipeline is restarted while the stream is still live
+	restartDelay = 10 * time.Second
+)
+
+// waitForLive polls Helix until the channel goes live. It returns false if ctx is canceled first.
+func (c *channel) waitForLive(ctx context.Context) (twitch.StreamInfo, bool) {
+	delay := offlinePollInterval
+
+	for {
+		stream, err := c.s.twitchClient.GetStream(c.username)
+
+		switch {
+		case err != nil:
+			c.logger.Warn("Failed to get stream status",
+				slog.Duration("retryIn", delay),
+				slog.Any("error", err),
+			)
+		case stream != nil:
+			return *stream, true
+		default:
+			c.logger.Debug("Channel is offline")
+		}
+
+		select {
+		case <-ctx.Done():
+			return twitch.StreamInfo{}, false
+		case <-time.After(delay):
+		}
+
+		if err != nil {
+			delay = min(delay*2, maxPollBackoff)
+		} else {
+			delay = offlinePollInterval
+		}
+	}
+}
+
+// waitForOffline polls Helix until the broadcast ends or is replaced by a new one, or until ctx is canceled
+func (c *channel) waitForOffline(ctx context.Context, streamID string) {
+	delay := onlinePollInterval
+	offline := 0
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+
+		stream, err := c.s.twitchClient.GetStream(c.username)
+		if err != nil {
+			delay = min(delay*2, maxPollBackoff)
+			c.logger.Warn("Failed to get stream status",
+				slog.Duration("retryIn", delay),
+				slog.Any("error", err),
+			)
+			continue
+		}
+
+		delay = onlinePollInterval
+
+		switch {
+		case stream == nil:
+			offline++
+			if offline >= offlineConfirmations {
+				return
+			}
+		case stream.ID != streamID:
+			c.logger.Info("Broadcast was restarted",
+				slog.String("streamId", stream.ID),
+			)
+			return
+		default:
+			offline = 0
+		}
+	}
+}
+
+// watchStream runs the pipeline while the broadcast is live, restarting it if it fails,
+// and lets it finish the audio it has once the broadcast ends
+func (c *channel) watchStream(ctx context.Context, stream twitch.StreamInfo) {
+	c.logger.Info("Stream is live",
+		slog.String("streamId", stream.ID),
+		slog.Time("startedAt", stream.StartedAt),
+		slog.String("title", stream.Title),
+		slog.String("game", stream.GameName),
+		slog.Bool("telegram", true),
+	)
+
+	c.startStreak(ctx, stream.ID)
+
+	c.stream.Store(&stream)
+	defer c.stream.Store(nil)
+
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	offline := make(chan struct{})
+	categoryDone := make(chan struct{})
+
+	go func() {
+		defer close(offline)
+		defer c.recoverPanic(cancel)
+		c.waitForOffline(watchCtx, stream.ID)
+	}()
+
+	go func() {
+		defer close(categoryDone)
+		defer c.recoverPanic(cancel)
+		c.watchCategory(watchCtx, stream.GameName)
+	}()
+
+	defer func() {
+		cancel()
+		<-categoryDone
+		c.clearCategory(ctx)
+	}()
+
+	for {
+		if err := runSafe(func() { c.processStream(ctx, stream, offline) }); err != nil {
+			c.logger.Error("Channel worker crashed",
+				slog.Any("error", err),
+			)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-offline:
+			c.logger.Info("Stream is offline",
+				slog.String("streamId", stream.ID),
+				slog.Duration("duration", c.s.clock.Now().Sub(stream.StartedAt).Round(time.Second)),
+				slog.Bool("telegram", true),
+			)
+			return
+		case <-time.After(restartDelay):
+		}
+	}
+}
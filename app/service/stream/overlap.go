@@ -0,0 +1,113 @@
+package stream
+
+import (
+	"strings"
+	"unicode"
+
+	"nicemaxxingbot/app/util/audio"
+)
+
+const (
+	// shortest run of words treated as a repeated overlap rather than a coincidence
+	minOverlapWords = 2
+	// how many words of the previous transcript are searched for the overlap
+	maxOverlapWords = 40
+	// words cut mid-way at a chunk boundary that Whisper may garble or drop
+	maxBoundaryWords = 2
+)
+
+// overlapper prepends the tail of the previous chunk to every chunk,
+// so that phrases cut at a chunk boundary are transcribed in full at least once
+type overlapper struct {
+	length int
+	tail   []byte
+}
+
+func (o *overlapper) apply(ch chunk) chunk {
+	if o.length <= 0 {
+		return ch
+	}
+
+	pcm := make([]byte, 0, len(o.tail)+len(ch.pcm))
+	pcm = append(pcm, o.tail...)
+	pcm = append(pcm, ch.pcm...)
+
+	o.tail = ch.pcm[max(0, len(ch.pcm)-o.length):]
+
+	return chunk{
+		index:  ch.index,
+		offset: ch.end() - audio.Duration(pcm),
+		pcm:    pcm,
+	}
+}
+
+// overlapDeduper removes the text repeated from the previous transcript in overlapping chunks,
+// transcripts have to be passed in chunk order
+type overlapDeduper struct {
+	last string
+}
+
+func (d *overlapDeduper) dedupe(text string) string {
+	result := dedupeOverlap(d.last, text)
+	d.last = text
+
+	return result
+}
+
+// dedupeOverlap drops the beginning of cur that repeats the end of prev.
+// Words at the boundary are cut mid-way, so up to maxBoundaryWords are allowed to differ
+// at the start of cur and at the end of prev.
+func dedupeOverlap(prev, cur string) string {
+	prevWords := splitWords(prev)
+	curWords := splitWords(cur)
+
+	prevWords = prevWords[max(0, len(prevWords)-maxOverlapWords):]
+
+	prevNorm := normalizeWords(prevWords)
+	curNorm := normalizeWords(curWords)
+
+	bestEnd := 0
+	bestLength := 0
+
+	for skip := 0; skip <= maxBoundaryWords && skip < len(curNorm); skip++ {
+		for start := range prevNorm {
+			length := 0
+			for start+length < len(prevNorm) && skip+length < len(curNorm) &&
+				prevNorm[start+length] != "" && prevNorm[start+length] == curNorm[skip+length] {
+				length++
+			}
+
+			// the match has to reach the end of prev, otherwise it is a repetition, not an overlap
+			if length < minOverlapWords || start+length < len(prevNorm)-maxBoundaryWords {
+				continue
+			}
+
+			if length > bestLength {
+				bestLength = length
+				bestEnd = skip + length
+			}
+		}
+	}
+
+	if bestLength == 0 {
+		return cur
+	}
+
+	return strings.Join(curWords[bestEnd:], " ")
+}
+
+func splitWords(text string) []string {
+	return strings.Fields(text)
+}
+
+func normalizeWords(words []string) []string {
+	result := make([]string, len(words))
+
+	for i, word := range words {
+		result[i] = strings.ToLower(strings.TrimFunc(word, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		}))
+	}
+
+	return result
+}
@@ -0,0 +1,74 @@
+package stream
+
+import (
+	"testing"
+	"time"
+
+	"nicemaxxingbot/app/util/audio"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDedupeOverlap(t *testing.T) {
+	tests := []struct {
+		name string
+		prev string
+		cur  string
+		want string
+	}{
+		{
+			name: "no previous transcript",
+			prev: "",
+			cur:  "Nurse players are not human",
+			want: "Nurse players are not human",
+		},
+		{
+			name: "exact overlap",
+			prev: "I swear, nurse players are not",
+			cur:  "nurse players are not human at all",
+			want: "human at all",
+		},
+		{
+			name: "garbled boundary words",
+			prev: "I swear, nurse players are not hu-",
+			cur:  "ear, nurse players are not human at all",
+			want: "human at all",
+		},
+		{
+			name: "punctuation and case differ",
+			prev: "okay so THE KILLER is camping.",
+			cur:  "The killer is camping, again",
+			want: "again",
+		},
+		{
+			name: "repetition in the middle is kept",
+			prev: "gg ez, nice game everyone, see you tomorrow",
+			cur:  "gg ez, that was close",
+			want: "gg ez, that was close",
+		},
+		{
+			name: "single word is a coincidence",
+			prev: "let's go",
+			cur:  "go go go",
+			want: "go go go",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, dedupeOverlap(tt.prev, tt.cur))
+		})
+	}
+}
+
+func TestOverlapper(t *testing.T) {
+	o := overlapper{length: audio.Bytes(5 * time.Second)}
+
+	first := o.apply(chunk{index: 0, offset: 0, pcm: make([]byte, audio.Bytes(30*time.Second))})
+	assert.Equal(t, time.Duration(0), first.offset)
+	assert.Equal(t, 30*time.Second, first.end())
+
+	second := o.apply(chunk{index: 1, offset: 30 * time.Second, pcm: make([]byte, audio.Bytes(30*time.Second))})
+	assert.Equal(t, 25*time.Second, second.offset)
+	assert.Equal(t, 60*time.Second, second.end())
+}
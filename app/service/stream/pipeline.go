@@ -0,0 +1,402 @@
+package stream
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"sync"
+	"time"
+
+	"nicemaxxingbot/app/client/whisper"
+	"nicemaxxingbot/app/service/state"
+	"nicemaxxingbot/app/util/audio"
+	"nicemaxxingbot/app/util/clock"
+	"nicemaxxingbot/app/util/prompt"
+
+	"go.opentelemetry.io/otel/attribute"
+	otelmetric "go.opentelemetry.io/otel/metric"
+)
+
+// runPipeline cuts the source into chunks with ffmpeg, transcribes them and feeds the text to the accumulator.
+// It returns once ffmpeg exits and every chunk it produced is processed.
+func (c *channel) runPipeline(ctx context.Context, src source) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// unbuffered, so that every text sent before close is already in the accumulator
+	textChan := make(chan string)
+
+	c.live.Store(true)
+	defer c.live.Store(false)
+
+	c.s.health.SetLive(c.username, true)
+	defer c.s.health.SetLive(c.username, false)
+	processingTimeout := time.Duration(c.s.cfg.Processing.BatchTimeout) * time.Second
+
+	c.timeline.reset()
+	c.recent.reset()
+
+	textProcessor := NewStringAccumulator(textChan, c.s.cfg.Processing.BatchSize, processingTimeout, c.s.clock, func(ctx context.Context, text string) {
+		defer c.recoverPanic(cancel)
+		c.processText(ctx, text)
+	})
+	textProcessor.Start(ctx)
+	defer textProcessor.Shutdown()
+
+	chunks := make(chan chunk)
+	ingestDone := make(chan error, 1)
+
+	// stopping the ingest lets the chunks already cut go through the rest of the pipeline
+	ingestCtx, stopIngest := context.WithCancel(ctx)
+	defer stopIngest()
+
+	go func() {
+		defer c.recoverPanic(cancel)
+
+		select {
+		case <-src.stop:
+			stopIngest()
+		case <-ingestCtx.Done():
+		}
+	}()
+
+	go func() {
+		defer close(chunks)
+
+		var err error
+		if panicErr := runSafe(func() { err = c.ingest(ingestCtx, src, chunks) }); panicErr != nil {
+			err = panicErr
+		}
+
+		ingestDone <- err
+	}()
+
+	count := c.processChunks(ctx, cancel, chunks, src, textChan)
+
+	// LastSeen of the last chunks may not be persisted yet, the resume window of the next stream starts from it
+	c.updateState(ctx, func(*state.ChannelState) {})
+
+	// every sender is done at this point, closing lets the accumulator flush the tail
+	close(textChan)
+
+	if err := <-ingestDone; err != nil && (ctx.Err() != nil || ingestCtx.Err() == nil) {
+		return err
+	}
+
+	c.logger.Info("Stream ended",
+		slog.Int("chunks", count),
+	)
+
+	return nil
+}
+
+const (
+	// a chunk that is not transcribed by then is given up, so it does not hold back later ones
+	transcribeTimeout = 2 * time.Minute
+)
+
+// chunkResult is a processed chunk waiting for its turn to be released
+type chunkResult struct {
+	ch            chunk
+	transcription whisper.Transcription
+	// end of the chunk on the clock
+	end time.Time
+	// start of the chunk into the stream
+	streamOffset time.Duration
+}
+
+// processChunks queues chunks for a pool of Whisper workers and releases the transcripts in chunk order.
+// The workers of all channels share Processing.WhisperConcurrency Whisper slots.
+// Chunks of live sources are dropped when Whisper falls behind, other sources wait for a free worker.
+// It returns the number of chunks once the channel is closed and every chunk is processed, a panic cancels the pipeline.
+func (c *channel) processChunks(ctx context.Context, cancel context.CancelFunc, chunks <-chan chunk, src source, textChan chan<- string) int {
+	pipelineStart := c.s.clock.Now()
+	count := 0
+
+	// how far into the stream the pipeline started
+	var startOffset time.Duration
+	if !src.startedAt.IsZero() {
+		startOffset = max(0, pipelineStart.Sub(src.startedAt))
+	}
+
+	overlap := overlapper{length: audio.Bytes(time.Duration(c.s.cfg.Processing.ChunkOverlap) * time.Second)}
+	dropPolicy := dropNone
+	if src.live {
+		dropPolicy = c.s.cfg.Processing.QueueDropPolicy
+	}
+
+	queue := newChunkQueue(c.s.cfg.Processing.QueueSize, dropPolicy)
+	// unbounded, so that ingest never waits for the release goroutine to take a dropped chunk
+	dropped := newChunkQueue(math.MaxInt, dropNone)
+	results := make(chan queuedChunk, c.s.cfg.Processing.WhisperConcurrency)
+	releaseDone := make(chan struct{})
+
+	go func() {
+		defer close(releaseDone)
+		// keeps the workers from blocking on a full results channel if releasing panicked
+		defer func() {
+			for range results {
+			}
+		}()
+		defer c.recoverPanic(cancel)
+
+		buffer := newReorderBuffer[chunkResult]()
+		// only used by this goroutine, transcripts arrive in order here
+		var deduper *overlapDeduper
+		if overlap.length > 0 {
+			deduper = &overlapDeduper{}
+		}
+
+		for item := range results {
+			ready := buffer.push(item.seq, item.result)
+			if len(ready) == 0 {
+				c.logger.Debug("Waiting for an earlier chunk",
+					slog.String("chunk", item.result.ch.name()),
+					slog.Int("waiting", buffer.waiting()),
+				)
+			}
+
+			for _, result := range ready {
+				c.releaseChunk(ctx, result, deduper, textChan)
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+
+	wg.Go(func() {
+		forwardChunks(dropped, results)
+	})
+
+	for range c.s.cfg.Processing.WhisperConcurrency {
+		wg.Go(func() {
+			for {
+				item, ok := queue.pop()
+				if !ok {
+					return
+				}
+
+				c.s.metrics.QueueDepth.Record(ctx, int64(queue.depth()), c.metricAttrs)
+
+				// the worker keeps going, so that the queue is drained and the reorder buffer gets the chunk
+				func() {
+					defer c.recoverPanic(cancel)
+
+					if !c.s.acquireWhisper(ctx) {
+						return
+					}
+					defer c.s.releaseWhisper()
+
+					item.result.transcription = c.transcribeChunk(ctx, item.result.ch)
+				}()
+
+				results <- item
+			}
+		})
+	}
+
+	for ch := range chunks {
+		ch = overlap.apply(ch)
+		seq := count
+		count++
+		c.s.metrics.ChunksProduced.Add(ctx, 1, c.metricAttrs)
+		c.s.health.MarkChunk(c.username)
+
+		c.logger.Info("Queueing chunk...",
+			slog.String("chunk", ch.name()),
+			slog.Duration("offset", ch.offset),
+		)
+
+		item, ok := queue.push(queuedChunk{
+			seq: seq,
+			result: chunkResult{
+				ch:           ch,
+				end:          pipelineStart.Add(ch.end()),
+				streamOffset: startOffset + ch.offset,
+			},
+		})
+		c.s.metrics.QueueDepth.Record(ctx, int64(queue.depth()), c.metricAttrs)
+
+		if ok {
+			c.s.metrics.ChunksDropped.Add(ctx, 1, c.metricAttrs)
+			c.logger.Warn("Dropping chunk",
+				slog.String("chunk", item.result.ch.name()),
+				slog.String("reason", fmt.Sprintf("transcription queue is full (%d chunks), dropping %s",
+					c.s.cfg.Processing.QueueSize, c.s.cfg.Processing.QueueDropPolicy)),
+			)
+
+			// the dropped chunk is released without text, so the reorder buffer does not wait for it.
+			// Its audio is not needed anymore and would pile up while the release goroutine is busy.
+			item.result.ch.pcm = nil
+			dropped.push(item)
+		}
+	}
+
+	queue.close()
+	dropped.close()
+	wg.Wait()
+	close(results)
+	<-releaseDone
+
+	return count
+}
+
+// forwardChunks moves chunks from the queue to out until the queue is closed and drained
+func forwardChunks(q *chunkQueue, out chan<- queuedChunk) {
+	for {
+		item, ok := q.pop()
+		if !ok {
+			return
+		}
+
+		out <- item
+	}
+}
+
+// acquireWhisper waits until fewer than Processing.WhisperConcurrency chunks of all channels are being transcribed.
+// It returns false if ctx is canceled first.
+func (s *Service) acquireWhisper(ctx context.Context) bool {
+	select {
+	case s.whisperSlots <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (s *Service) releaseWhisper() {
+	<-s.whisperSlots
+}
+
+// transcribeChunk processes the chunk and logs failures, failed chunks have no text
+func (c *channel) transcribeChunk(ctx context.Context, ch chunk) whisper.Transcription {
+	transcription, err := c.processChunk(ctx, ch)
+	if err != nil {
+		c.s.metrics.ChunksFailed.Add(ctx, 1, c.metricAttrs)
+		c.logger.Error("Failed to process chunk",
+			slog.String("chunk", ch.name()),
+			slog.Any("error", err),
+		)
+		return whisper.Transcription{}
+	}
+
+	c.logger.Info("Chunk processed",
+		slog.String("chunk", ch.name()),
+	)
+
+	return transcription
+}
+
+func (c *channel) transcribe(ctx context.Context, ch chunk) (whisper.Transcription, error) {
+	slogger := c.logger.With(slog.String("chunk", ch.name()))
+
+	var whisperPrompt prompt.Prompt
+	if c.s.whisperPrompt != nil {
+		var err error
+
+		whisperPrompt, err = c.s.whisperPrompt.Render(c.promptVars())
+		if err != nil {
+			return whisper.Transcription{}, fmt.Errorf("Render: %w", err)
+		}
+
+		slogger = slogger.With(slog.String("promptHash", whisperPrompt.Hash))
+	}
+
+	start := time.Now()
+	slogger.Debug("Transcribing chunk...")
+
+	transcription, err := c.s.whisperClient.TranscribeWithPrompt(ctx, bytes.NewReader(audio.EncodeWAV(ch.pcm)), ch.name(), whisperPrompt.Text)
+	if err != nil {
+		return whisper.Transcription{}, fmt.Errorf("Transcribe: %w", err)
+	}
+
+	slogger = slogger.With(slog.String("text", transcription.Text))
+	slogger.Debug("Got text",
+		slog.Duration("duration", time.Since(start)),
+		slog.Int("words", len(transcription.Words)),
+	)
+
+	return transcription, nil
+}
+
+// hasSpeech runs voice activity detection on the chunk, Whisper hallucinates on silence and music
+func (c *channel) hasSpeech(ch chunk) bool {
+	if c.s.cfg.Processing.DisableVAD {
+		return true
+	}
+
+	vad := audio.VAD{EnergyThreshold: c.s.cfg.Processing.SilenceThreshold}
+	ratio := vad.SpeechRatio(ch.pcm)
+
+	if ratio < c.s.cfg.Processing.VADThreshold {
+		c.logger.Debug("Skipping chunk without speech",
+			slog.String("chunk", ch.name()),
+			slog.Float64("speechRatio", ratio),
+		)
+		return false
+	}
+
+	return true
+}
+
+// processChunk transcribes the chunk, chunks without speech or in an ignored category are skipped with an empty text
+func (c *channel) processChunk(ctx context.Context, ch chunk) (whisper.Transcription, error) {
+	if c.categoryRule().ignored() {
+		c.logger.Debug("Skipping chunk, the category is ignored",
+			slog.String("chunk", ch.name()),
+		)
+		c.s.metrics.ChunksSkipped.Add(ctx, 1, c.metricAttrs, otelmetric.WithAttributes(attribute.String("reason", "category")))
+		return whisper.Transcription{}, nil
+	}
+
+	if !c.hasSpeech(ch) {
+		c.s.metrics.ChunksSkipped.Add(ctx, 1, c.metricAttrs, otelmetric.WithAttributes(attribute.String("reason", "vad")))
+		return whisper.Transcription{}, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, transcribeTimeout)
+	defer cancel()
+
+	transcription, err := c.transcribe(ctx, ch)
+	if err != nil {
+		return whisper.Transcription{}, fmt.Errorf("transcribe: %w", err)
+	}
+
+	c.s.metrics.ChunksTranscribed.Add(ctx, 1, c.metricAttrs)
+
+	return transcription, nil
+}
+
+// releaseChunk moves the stream forward to the end of the chunk and sends its transcript to the accumulator
+func (c *channel) releaseChunk(ctx context.Context, result chunkResult, deduper *overlapDeduper, textChan chan<- string) {
+	// replays run faster than real time, so the clock follows the media instead
+	if simulated, ok := c.s.clock.(*clock.Simulated); ok {
+		simulated.AdvanceTo(result.end)
+	}
+
+	streak := c.touchLastSeen(ctx)
+	c.s.metrics.StreakLength.Record(ctx, streak.Seconds(), c.metricAttrs)
+	c.checkQuietHours()
+
+	c.timeline.add(result.streamOffset, result.streamOffset+audio.Duration(result.ch.pcm), result.transcription.Words)
+
+	text := result.transcription.Text
+	if deduper != nil && text != "" {
+		text = deduper.dedupe(text)
+	}
+
+	if c.s.evidence != nil && result.transcription.Text != "" {
+		c.recent.add(result, text)
+	}
+
+	if text == "" {
+		return
+	}
+
+	select {
+	case <-ctx.Done():
+	case textChan <- text:
+	}
+}
@@ -0,0 +1,103 @@
+package stream
+
+import (
+	"sync"
+)
+
+const (
+	// dropNone makes push wait for a free slot, for sources that can be read at any pace
+	dropNone = ""
+	// dropOldest discards the longest waiting chunk when the queue is full, keeping the stream close to real time
+	dropOldest = "oldest"
+	// dropNewest discards the incoming chunk when the queue is full, keeping the transcript contiguous
+	dropNewest = "newest"
+)
+
+// queuedChunk is a chunk waiting for a transcription worker
+type queuedChunk struct {
+	seq    int
+	result chunkResult
+}
+
+// chunkQueue is a bounded FIFO of chunks waiting for transcription
+type chunkQueue struct {
+	maxSize int
+	policy  string
+
+	m      sync.Mutex
+	cond   *sync.Cond
+	items  []queuedChunk
+	closed bool
+}
+
+func newChunkQueue(maxSize int, policy string) *chunkQueue {
+	q := &chunkQueue{
+		maxSize: maxSize,
+		policy:  policy,
+	}
+	q.cond = sync.NewCond(&q.m)
+
+	return q
+}
+
+// push adds the chunk to the queue. If the queue is full, the chunk dropped by the policy
+// is returned with ok set to true.
+func (q *chunkQueue) push(item queuedChunk) (dropped queuedChunk, ok bool) {
+	q.m.Lock()
+	defer q.m.Unlock()
+
+	for q.policy == dropNone && len(q.items) >= q.maxSize {
+		q.cond.Wait()
+	}
+
+	if len(q.items) >= q.maxSize {
+		if q.policy == dropNewest {
+			return item, true
+		}
+
+		dropped, ok = q.items[0], true
+		q.items = q.items[1:]
+	}
+
+	q.items = append(q.items, item)
+	q.cond.Broadcast()
+
+	return dropped, ok
+}
+
+// pop waits for the next chunk, ok is false once the queue is closed and drained
+func (q *chunkQueue) pop() (item queuedChunk, ok bool) {
+	q.m.Lock()
+	defer q.m.Unlock()
+
+	for len(q.items) == 0 && !q.closed {
+		q.cond.Wait()
+	}
+
+	if len(q.items) == 0 {
+		return item, false
+	}
+
+	item = q.items[0]
+	q.items = q.items[1:]
+	q.cond.Broadcast()
+
+	return item, true
+}
+
+// depth returns the number of chunks waiting for a worker
+func (q *chunkQueue) depth() int {
+	q.m.Lock()
+	defer q.m.Unlock()
+
+	return len(q.items)
+}
+
+// close wakes up the workers, they drain the remaining chunks and exit
+func (q *chunkQueue) close() {
+	q.m.Lock()
+	defer q.m.Unlock()
+
+	q.closed = true
+	q.cond.Broadcast()
+}
@@ -0,0 +1,113 @@
+package stream
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func pushSeqs(q *chunkQueue, seqs ...int) []int {
+	var dropped []int
+
+	for _, seq := range seqs {
+		if item, ok := q.push(queuedChunk{seq: seq}); ok {
+			dropped = append(dropped, item.seq)
+		}
+	}
+
+	return dropped
+}
+
+func popAll(q *chunkQueue) []int {
+	q.close()
+
+	var seqs []int
+
+	for {
+		item, ok := q.pop()
+		if !ok {
+			return seqs
+		}
+		seqs = append(seqs, item.seq)
+	}
+}
+
+func TestChunkQueue_DropOldest(t *testing.T) {
+	q := newChunkQueue(2, dropOldest)
+
+	assert.Equal(t, []int{0, 1}, pushSeqs(q, 0, 1, 2, 3))
+	assert.Equal(t, 2, q.depth())
+	assert.Equal(t, []int{2, 3}, popAll(q))
+}
+
+func TestChunkQueue_DropNewest(t *testing.T) {
+	q := newChunkQueue(2, dropNewest)
+
+	assert.Equal(t, []int{2, 3}, pushSeqs(q, 0, 1, 2, 3))
+	assert.Equal(t, []int{0, 1}, popAll(q))
+}
+
+func TestChunkQueue_DropNone(t *testing.T) {
+	q := newChunkQueue(1, dropNone)
+
+	assert.Empty(t, pushSeqs(q, 0))
+
+	pushed := make(chan struct{})
+	go func() {
+		defer close(pushed)
+		pushSeqs(q, 1)
+	}()
+
+	item, ok := q.pop()
+	assert.True(t, ok)
+	assert.Equal(t, 0, item.seq)
+
+	<-pushed
+	assert.Equal(t, []int{1}, popAll(q))
+}
+
+func TestForwardChunks_StalledConsumer(t *testing.T) {
+	q := newChunkQueue(2, dropOldest)
+	dropped := newChunkQueue(math.MaxInt, dropNone)
+	// nobody reads the results until every chunk is queued, like a release goroutine stuck on the LLM
+	results := make(chan queuedChunk, 1)
+
+	forwarded := make(chan struct{})
+	go func() {
+		defer close(forwarded)
+		forwardChunks(dropped, results)
+	}()
+
+	ingested := make(chan struct{})
+	go func() {
+		defer close(ingested)
+
+		for seq := range 100 {
+			if item, ok := q.push(queuedChunk{seq: seq}); ok {
+				dropped.push(item)
+			}
+		}
+		dropped.close()
+	}()
+
+	select {
+	case <-ingested:
+	case <-time.After(time.Second):
+		require.FailNow(t, "ingest is blocked by the stalled consumer")
+	}
+
+	var seqs []int
+	for len(seqs) < 98 {
+		item := <-results
+		seqs = append(seqs, item.seq)
+	}
+	<-forwarded
+
+	for i, seq := range seqs {
+		assert.Equal(t, i, seq)
+	}
+	assert.Equal(t, []int{98, 99}, popAll(q))
+}
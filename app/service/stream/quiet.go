@@ -0,0 +1,125 @@
+package stream
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"nicemaxxingbot/app/config"
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// quietWindow is a recurring time of day when streak notifications are not sent
+type quietWindow struct {
+	// minutes since midnight
+	from, to int
+	// days the window starts on, every day if empty
+	days map[time.Weekday]bool
+}
+
+// quietHours are the quiet windows of a channel in its timezone
+type quietHours struct {
+	loc     *time.Location
+	windows []quietWindow
+}
+
+func parseQuietHours(loc *time.Location, cfg []config.QuietHours) (quietHours, error) {
+	q := quietHours{loc: loc}
+
+	for _, window := range cfg {
+		from, err := parseClock(window.From)
+		if err != nil {
+			return quietHours{}, err
+		}
+
+		to, err := parseClock(window.To)
+		if err != nil {
+			return quietHours{}, err
+		}
+
+		w := quietWindow{from: from, to: to}
+
+		if len(window.Days) > 0 {
+			w.days = make(map[time.Weekday]bool, len(window.Days))
+
+			for _, day := range window.Days {
+				weekday, ok := weekdays[day]
+				if !ok {
+					return quietHours{}, fmt.Errorf("unknown day %q", day)
+				}
+
+				w.days[weekday] = true
+			}
+		}
+
+		q.windows = append(q.windows, w)
+	}
+
+	return q, nil
+}
+
+// parseClock parses HH:MM into minutes since midnight
+func parseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, use HH:MM: %w", value, err)
+	}
+
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// until returns the end of the quiet window now is in, false if now is not in quiet hours
+func (q quietHours) until(now time.Time) (time.Time, bool) {
+	now = now.In(q.loc)
+
+	var end time.Time
+
+	for _, w := range q.windows {
+		// a window that started yesterday may still be going on
+		for _, day := range []time.Time{now.AddDate(0, 0, -1), now} {
+			if w.days != nil && !w.days[day.Weekday()] {
+				continue
+			}
+
+			start := time.Date(day.Year(), day.Month(), day.Day(), w.from/60, w.from%60, 0, 0, q.loc)
+			windowEnd := time.Date(day.Year(), day.Month(), day.Day(), w.to/60, w.to%60, 0, 0, q.loc)
+			if w.to <= w.from {
+				windowEnd = windowEnd.AddDate(0, 0, 1)
+			}
+
+			if !now.Before(start) && now.Before(windowEnd) && windowEnd.After(end) {
+				end = windowEnd
+			}
+		}
+	}
+
+	return end, !end.IsZero()
+}
+
+// checkQuietHours logs when quiet hours start and end
+func (c *channel) checkQuietHours() {
+	until, quiet := c.quiet.until(c.s.clock.Now())
+	if c.inQuietHours.Swap(quiet) == quiet {
+		return
+	}
+
+	if quiet {
+		c.logger.Info("Quiet hours started",
+			slog.Time("quietUntil", until),
+			slog.Bool("telegram", true),
+		)
+	} else {
+		c.logger.Info("Quiet hours ended",
+			slog.Bool("telegram", true),
+		)
+	}
+}
@@ -0,0 +1,57 @@
+package stream
+
+import (
+	"testing"
+	"time"
+
+	"nicemaxxingbot/app/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuietHours_Until(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Moscow")
+	require.NoError(t, err)
+
+	quiet, err := parseQuietHours(loc, []config.QuietHours{
+		{From: "23:00", To: "08:00"},
+		{From: "12:00", To: "14:00", Days: []string{"sat", "sun"}},
+	})
+	require.NoError(t, err)
+
+	// 2025-09-05 is a Friday
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2025, 9, day, hour, minute, 0, 0, loc)
+	}
+
+	tests := []struct {
+		name  string
+		now   time.Time
+		until time.Time
+		quiet bool
+	}{
+		{name: "before midnight", now: at(5, 23, 30), until: at(6, 8, 0), quiet: true},
+		{name: "after midnight", now: at(6, 7, 59), until: at(6, 8, 0), quiet: true},
+		{name: "window end", now: at(6, 8, 0), quiet: false},
+		{name: "friday noon", now: at(5, 13, 0), quiet: false},
+		{name: "saturday noon", now: at(6, 13, 0), until: at(6, 14, 0), quiet: true},
+		{name: "other timezone", now: at(6, 13, 0).UTC(), until: at(6, 14, 0), quiet: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			until, quiet := quiet.until(tt.now)
+			assert.Equal(t, tt.quiet, quiet)
+			assert.True(t, tt.until.Equal(until), "expected %s, got %s", tt.until, until)
+		})
+	}
+}
+
+func TestParseQuietHours_Invalid(t *testing.T) {
+	_, err := parseQuietHours(time.UTC, []config.QuietHours{{From: "25:00", To: "08:00"}})
+	assert.Error(t, err)
+
+	_, err = parseQuietHours(time.UTC, []config.QuietHours{{From: "23:00", To: "08:00", Days: []string{"someday"}}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,36 @@
+package stream
+
+// reorderBuffer releases results of concurrently processed chunks in sequence order
+type reorderBuffer[T any] struct {
+	next    int
+	pending map[int]T
+}
+
+func newReorderBuffer[T any]() *reorderBuffer[T] {
+	return &reorderBuffer[T]{
+		pending: make(map[int]T),
+	}
+}
+
+// push stores the result of the chunk and returns the results that are ready, in order
+func (b *reorderBuffer[T]) push(seq int, result T) []T {
+	b.pending[seq] = result
+
+	var ready []T
+
+	for {
+		next, ok := b.pending[b.next]
+		if !ok {
+			return ready
+		}
+
+		delete(b.pending, b.next)
+		b.next++
+		ready = append(ready, next)
+	}
+}
+
+// waiting returns the number of results held back by an unfinished earlier chunk
+func (b *reorderBuffer[T]) waiting() int {
+	return len(b.pending)
+}
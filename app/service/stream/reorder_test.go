@@ -0,0 +1,22 @@
+package stream
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReorderBuffer(t *testing.T) {
+	b := newReorderBuffer[string]()
+
+	assert.Empty(t, b.push(1, "one"))
+	assert.Empty(t, b.push(2, "two"))
+	assert.Equal(t, 2, b.waiting())
+
+	assert.Equal(t, []string{"zero", "one", "two"}, b.push(0, "zero"))
+	assert.Equal(t, 0, b.waiting())
+
+	assert.Equal(t, []string{"three"}, b.push(3, "three"))
+	assert.Empty(t, b.push(5, "five"))
+	assert.Equal(t, []string{"four", "five"}, b.push(4, "four"))
+}
@@ -3,407 +3,234 @@ package stream
 import (
 	"context"
 	"fmt"
+	"io"
 	"log/slog"
+	"nicemaxxingbot/app/client/hls"
+	"nicemaxxingbot/app/client/openai"
 	"nicemaxxingbot/app/client/twitch"
 	"nicemaxxingbot/app/client/twitch_live"
 	"nicemaxxingbot/app/client/whisper"
-	"os"
-	"os/exec"
-	"path/filepath"
-	"slices"
+	"runtime/debug"
 	"sync"
 	"time"
 
 	"nicemaxxingbot/app/config"
+	"nicemaxxingbot/app/service/evidence"
+	"nicemaxxingbot/app/service/health"
+	"nicemaxxingbot/app/service/message"
+	"nicemaxxingbot/app/service/state"
 	"nicemaxxingbot/app/service/toxic"
+	"nicemaxxingbot/app/util/clock"
+	"nicemaxxingbot/app/util/prompt"
+	"nicemaxxingbot/app/util/telemetry"
 
-	"github.com/elliotchance/pie/v2"
-	"github.com/ozgio/strutil"
 	"github.com/samber/do"
 )
 
-const maxMessageLength = 400
 const dataDir = "data"
-const notificationFormat = "Nicemaxxing streak is over pingus It lasted for ~%d minutes pingus Toxic phrase: %s"
-const turnOffText = "pingus Bot is muted for 12 hours pingus"
-const turnOnText = "pingus Bot is back in action pingus"
+
+// Notifier delivers messages to the channel chat
+type Notifier interface {
+	SendMessage(channel, text string) error
+}
+
+// VideoArchive finds the VOD that is recorded for a broadcast
+type VideoArchive interface {
+	GetArchiveVideoID(username, streamID string) (string, error)
+}
 
 type Service struct {
 	cfg              *config.Config
+	notifier         Notifier
+	messages         *message.Templates
 	twitchClient     *twitch.Client
+	videos           VideoArchive
 	whisperClient    *whisper.Client
 	twitchLiveClient *twitch_live.Client
+	hlsClient        *hls.Client
 	toxicService     *toxic.Service
-
-	m           sync.Mutex
-	savedTime   time.Time
-	turnOffTime time.Time
-
-	textChan chan string
+	stateStore       state.Store
+	history          state.History
+	evidence         *evidence.Store
+	metrics          *telemetry.Metrics
+	health           *health.Service
+	clock            clock.Clock
+	categories       *categoryRules
+	classifierPrompt *prompt.Template
+	// nil if Whisper gets no prompt
+	whisperPrompt *prompt.Template
+	// limits Whisper requests of all channels together to Processing.WhisperConcurrency
+	whisperSlots chan struct{}
+
+	channels []*channel
 	wg       sync.WaitGroup
 }
 
 func New(di *do.Injector) (*Service, error) {
-	return &Service{
+	s := &Service{
 		cfg:              do.MustInvoke[*config.Config](di),
+		notifier:         do.MustInvoke[*twitch.Client](di),
+		messages:         do.MustInvoke[*message.Templates](di),
 		twitchClient:     do.MustInvoke[*twitch.Client](di),
+		videos:           do.MustInvoke[*twitch.Client](di),
 		whisperClient:    do.MustInvoke[*whisper.Client](di),
 		twitchLiveClient: do.MustInvoke[*twitch_live.Client](di),
+		hlsClient:        do.MustInvoke[*hls.Client](di),
 		toxicService:     do.MustInvoke[*toxic.Service](di),
-		textChan:         make(chan string, 1),
-	}, nil
-}
-
-func (s *Service) Run(ctx context.Context) {
-	defer close(s.textChan)
-	defer s.wg.Wait()
-
-	s.wg.Go(func() {
-		s.runWorker(ctx)
-	})
-}
-
-func (s *Service) runWorker(ctx context.Context) {
-	ticker := time.NewTicker(time.Minute)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		default:
-			s.processStream(ctx)
-
-			select {
-			case <-ctx.Done():
-				return
-			case <-ticker.C:
-				continue
-			}
-		}
-	}
-}
-
-func (s *Service) processStream(ctx context.Context) {
-	ctx, cancel := context.WithCancel(ctx)
-	defer cancel()
-
-	streamQualityArr, err := s.twitchLiveClient.GetM3U8(ctx, s.cfg.Streamer)
-	if err != nil {
-		slog.Warn("Failed to get stream URL",
-			slog.String("error", err.Error()),
-		)
-		return
+		stateStore:       do.MustInvoke[state.Store](di),
+		history:          do.MustInvoke[state.History](di),
+		metrics:          do.MustInvoke[*telemetry.Metrics](di),
+		health:           do.MustInvoke[*health.Service](di),
+		clock:            clock.Real{},
 	}
+	s.whisperSlots = make(chan struct{}, s.cfg.Processing.WhisperConcurrency)
 
-	if len(streamQualityArr) == 0 {
-		slog.Warn("No stream URL found")
-		return
+	if s.cfg.Evidence.Enabled {
+		s.evidence = do.MustInvoke[*evidence.Store](di)
 	}
 
-	processingTimeout := time.Duration(s.cfg.Processing.BatchTimeout) * time.Second
-
-	textProcessor := NewStringAccumulator(s.textChan, s.cfg.Processing.BatchSize, processingTimeout, s.processText)
-	textProcessor.Start(ctx)
-	defer textProcessor.Shutdown()
-
-	s.m.Lock()
-	s.savedTime = time.Now()
-	s.m.Unlock()
-
-	streamQualityIndex := pie.FindFirstUsing(streamQualityArr, func(q twitch_live.StreamQuality) bool {
-		return q.Quality == "audio_only"
-	})
-	if streamQualityIndex < 0 {
-		streamQualityIndex = 0
+	if err := s.loadPrompts(); err != nil {
+		return nil, err
 	}
-	streamQuality := streamQualityArr[streamQualityIndex]
-
-	slog.Info("Got stream URL",
-		slog.String("quality", streamQuality.Quality),
-		slog.String("resolution", streamQuality.Resolution),
-		slog.String("url", streamQuality.URL),
-		slog.Bool("telegram", true),
-	)
-
-	m3u8URL := streamQuality.URL
-
-	_ = os.RemoveAll(dataDir)
 
-	if err = os.MkdirAll(dataDir, 0755); err != nil {
-		slog.Error("Failed to create data dir",
-			slog.Any("error", err),
-		)
-		return
-	}
-
-	args := []string{
-		"-hwaccel", "auto",
-		"-timeout", "10000000", // 10s
-		"-reconnect", "0",
-		"-reconnect_at_eof", "0",
-		"-reconnect_streamed", "0",
-		"-reconnect_delay_max", "0",
-		"-i", m3u8URL,
-		"-f", "segment",
-		"-segment_time", "30",
-		"-reset_timestamps", "1",
-		"-ac", "1",
-		"-ar", "16000",
-		"-c:a", "pcm_s16le",
-		filepath.Join(dataDir, "chunk_%04d.wav"),
+	categories, err := loadCategoryRules(s.cfg.Categories)
+	if err != nil {
+		return nil, fmt.Errorf("loadCategoryRules: %w", err)
 	}
+	s.categories = categories
 
-	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
-	defer cmd.Cancel()
-
-	go func() {
-		defer cancel()
-
-		if err := cmd.Run(); err != nil {
-			slog.Error("FFMpeg failed",
-				slog.Any("error", err),
-			)
-			return
+	for _, streamer := range s.cfg.Streamers {
+		ch, err := newChannel(s, streamer)
+		if err != nil {
+			return nil, fmt.Errorf("newChannel: %w", err)
 		}
-	}()
 
-	if err = s.processChunks(ctx, dataDir); err != nil {
-		slog.Error("Failed to process chunks",
-			slog.Any("error", err),
-		)
+		s.channels = append(s.channels, ch)
 	}
 
-	cancel()
+	return s, nil
 }
 
-func (s *Service) processText(ctx context.Context, text string) {
-	slogger := slog.With(
-		slog.String("text", text),
-	)
+// NewReplay creates a service that runs recorded media through the pipeline on a simulated clock,
+// without touching Twitch or the persistent state. The clock starts at start, so that quiet hours and mutes
+// give the same results on every run. Notifications are written to out.
+func NewReplay(di *do.Injector, out io.Writer, start time.Time) (*Service, error) {
+	s := &Service{
+		cfg:           do.MustInvoke[*config.Config](di),
+		notifier:      &writerNotifier{out: out},
+		messages:      do.MustInvoke[*message.Templates](di),
+		whisperClient: do.MustInvoke[*whisper.Client](di),
+		toxicService:  do.MustInvoke[*toxic.Service](di),
+		stateStore:    state.NewMemoryStore(),
+		history:       state.NewMemoryHistory(),
+		metrics:       do.MustInvoke[*telemetry.Metrics](di),
+		health:        do.MustInvoke[*health.Service](di),
+		clock:         clock.NewSimulated(start),
+	}
+	s.whisperSlots = make(chan struct{}, s.cfg.Processing.WhisperConcurrency)
+
+	if err := s.loadPrompts(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
 
-	slogger.Info("Processing text...")
-	toxicResult, err := s.toxicService.ProcessTranscription(ctx, text)
+// loadPrompts parses the configured prompt templates, the classifier falls back to the built-in prompt
+func (s *Service) loadPrompts() error {
+	classifierPrompt, err := prompt.Load("classifier", s.cfg.Prompts.Classifier, openai.SystemPrompt)
 	if err != nil {
-		slogger.Error("Failed to process transcription",
-			slog.Any("error", err),
-		)
-		return
-	}
-
-	if toxicResult.TurnOff {
-		slogger.Info("Requested to turn the bot OFF",
-			slog.Bool("telegram", true),
-		)
-
-		if !s.cfg.Twitch.DisableNotifications {
-			if err = s.twitchClient.SendMessage(s.cfg.Streamer, turnOffText); err != nil {
-				slogger.Error("Failed to send turn off notification",
-					slog.String("phrase", toxicResult.Phrase),
-				)
-				return
-			}
-		}
-
-		s.m.Lock()
-		s.turnOffTime = time.Now().Add(12 * time.Hour)
-		s.m.Unlock()
-
-		return
-	}
-
-	if toxicResult.TurnOn {
-		slogger.Info("Requested to turn the bot ON",
-			slog.Bool("telegram", true),
-		)
-
-		if !s.cfg.Twitch.DisableNotifications {
-			if err = s.twitchClient.SendMessage(s.cfg.Streamer, turnOnText); err != nil {
-				slogger.Error("Failed to send turn on notification",
-					slog.String("phrase", toxicResult.Phrase),
-				)
-				return
-			}
-		}
-
-		s.m.Lock()
-		s.turnOffTime = time.Time{}
-		s.m.Unlock()
-
-		return
+		return fmt.Errorf("failed to load classifier prompt: %w", err)
 	}
 
-	if !toxicResult.Toxic {
-		return
-	}
-
-	if s.cfg.Twitch.DisableNotifications {
-		slogger.Info("Found toxic phrase, but notifications are disabled",
-			slog.String("phrase", toxicResult.Phrase),
-			slog.Bool("telegram", true),
-		)
-		return
-	}
-
-	s.m.Lock()
-	savedTime := s.savedTime
-	s.savedTime = time.Now()
-	turnOffTime := s.turnOffTime
-	s.m.Unlock()
-
-	if savedTime.IsZero() {
-		slogger.Error("No saved time found")
-		return
-	}
-
-	streakDuration := time.Since(savedTime)
-	streakDurationMinutes := int(streakDuration.Minutes())
-
-	if streakDurationMinutes < s.cfg.Twitch.MinStreakLength {
-		slogger.Info("Found toxic phrase, but streak is too low",
-			slog.String("phrase", toxicResult.Phrase),
-			slog.Bool("telegram", true),
-		)
-		return
-	}
-
-	if time.Now().Before(turnOffTime) {
-		slogger.Info("Found toxic phrase, but bot is temporarily disabled",
-			slog.String("phrase", toxicResult.Phrase),
-			slog.Bool("telegram", true),
-		)
-		return
+	whisperPrompt, err := prompt.Load("whisper", s.cfg.Prompts.Whisper, "")
+	if err != nil {
+		return fmt.Errorf("failed to load whisper prompt: %w", err)
 	}
 
-	notificationText := fmt.Sprintf(notificationFormat, streakDurationMinutes, toxicResult.Phrase)
-	notificationText = strutil.Summary(notificationText, maxMessageLength, "...")
+	s.classifierPrompt = classifierPrompt
+	s.whisperPrompt = whisperPrompt
 
-	if err = s.twitchClient.SendMessage(s.cfg.Streamer, notificationText); err != nil {
-		slogger.Error("Failed to send notification",
-			slog.String("phrase", toxicResult.Phrase),
-		)
-		return
-	}
-
-	slogger.Info("Found toxic phrase",
-		slog.String("phrase", toxicResult.Phrase),
-		slog.Bool("telegram", true),
-	)
+	return nil
 }
 
-func (s *Service) processChunks(ctx context.Context, dataDir string) error {
-	processedMap := make(map[string]struct{})
-	lastNewChunkTime := time.Now()
-	checkInterval := 5 * time.Second
-	timeoutDuration := 2 * time.Minute
-
-	for {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-			files, err := filepath.Glob(filepath.Join(dataDir, "*.wav"))
-			if err != nil {
-				return fmt.Errorf("filepath.Glob: %w", err)
-			}
-
-			slices.Sort(files)
-			fileCount := len(files)
-
-			newChunkFound := false
-			processedCount := 0
+func (s *Service) Run(ctx context.Context) {
+	defer s.wg.Wait()
 
-			for i, file := range files {
-				file := file
+	for _, ch := range s.channels {
+		s.wg.Go(func() {
+			for {
+				if err := runSafe(func() { ch.run(ctx) }); err != nil {
+					ch.logger.Error("Channel worker crashed",
+						slog.Any("error", err),
+						slog.Bool("telegram", true),
+					)
+				}
 
 				select {
 				case <-ctx.Done():
-					return ctx.Err()
-				default:
+					return
+				case <-time.After(restartDelay):
 				}
-
-				// skip last file, as it might be incomplete
-				if i == fileCount-1 {
-					continue
-				}
-
-				if _, ok := processedMap[file]; ok {
-					continue
-				}
-
-				newChunkFound = true
-				processedMap[file] = struct{}{}
-				processedCount++
-
-				slog.Info("Processing chunk...",
-					slog.String("file", file),
-				)
-
-				go func() {
-					if err := s.processChunk(ctx, file); err != nil {
-						slog.Error("Failed to process chunk",
-							slog.String("file", file),
-							slog.Any("error", err),
-						)
-						return
-					}
-
-					slog.Info("Chunk processed",
-						slog.String("file", file),
-					)
-				}()
-			}
-
-			if newChunkFound {
-				lastNewChunkTime = time.Now()
-				slog.Debug("Got new chunks",
-					slog.Int("count", processedCount),
-					slog.Time("lastNewChunkTime", lastNewChunkTime),
-				)
-			} else {
-				if time.Since(lastNewChunkTime) > timeoutDuration {
-					return fmt.Errorf("no new chunks found for %v", timeoutDuration)
-				}
-
-				slog.Debug("No new chunks found",
-					slog.Duration("timeSinceLastChunk", time.Since(lastNewChunkTime)),
-					slog.Duration("timeoutIn", timeoutDuration-time.Since(lastNewChunkTime)),
-				)
 			}
-
-			time.Sleep(checkInterval)
-		}
+		})
 	}
 }
 
-func (s *Service) transcribe(ctx context.Context, filePath string) (string, error) {
-	slogger := slog.With(slog.String("filePath", filePath))
-
-	start := time.Now()
-	slogger.Debug("Transcribing file...")
-
-	text, err := s.whisperClient.TranscribeFile(ctx, filePath)
+// Replay runs the local media file through the pipeline using the given streamer settings
+func (s *Service) Replay(ctx context.Context, streamer config.Streamer, inputPath string) error {
+	ch, err := newChannel(s, streamer)
 	if err != nil {
-		return "", fmt.Errorf("TranscribeFile: %w", err)
+		return err
 	}
 
-	slogger = slog.With(slog.String("text", text))
-	slogger.Debug("Got text",
-		slog.Duration("duration", time.Since(start)),
-	)
+	ch.loadState(ctx)
+	ch.startStreak(ctx, "")
 
-	return text, nil
+	return ch.runPipeline(ctx, source{
+		input: inputPath,
+		live:  false,
+	})
 }
 
-func (s *Service) processChunk(ctx context.Context, filePath string) error {
-	defer os.Remove(filePath)
+// runSafe runs fn and converts a panic into an error, so that one broken channel
+// does not bring down the others. Goroutines started by fn recover with recoverPanic.
+func runSafe(fn func()) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
 
-	text, err := s.transcribe(ctx, filePath)
-	if err != nil {
-		return fmt.Errorf("transcribe: %w", err)
+	fn()
+
+	return nil
+}
+
+// recoverPanic is deferred in every goroutine of the channel, a panic is logged and cancels the
+// pipeline instead of crashing the process with every other channel in it
+func (c *channel) recoverPanic(cancel context.CancelFunc) {
+	if r := recover(); r != nil {
+		c.logger.Error("Channel worker crashed",
+			slog.Any("error", fmt.Errorf("panic: %v", r)),
+			slog.String("stack", string(debug.Stack())),
+			slog.Bool("telegram", true),
+		)
+		cancel()
 	}
+}
 
-	s.textChan <- text
+// writerNotifier prints notifications instead of sending them to the chat
+type writerNotifier struct {
+	m   sync.Mutex
+	out io.Writer
+}
 
-	return nil
+func (n *writerNotifier) SendMessage(channel, text string) error {
+	n.m.Lock()
+	defer n.m.Unlock()
+
+	_, err := fmt.Fprintf(n.out, "[#%s] %s\n", channel, text)
+
+	return err
 }
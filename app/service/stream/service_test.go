@@ -0,0 +1,49 @@
+package stream
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecoverPanic(t *testing.T) {
+	c := &channel{logger: slog.Default()}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		defer c.recoverPanic(cancel)
+
+		panic("broken chunk")
+	}()
+
+	<-done
+	assert.ErrorIs(t, ctx.Err(), context.Canceled)
+}
+
+func TestRunSafe(t *testing.T) {
+	err := runSafe(func() { panic("broken channel") })
+	assert.EqualError(t, err, "panic: broken channel")
+
+	assert.NoError(t, runSafe(func() {}))
+}
+
+func TestAcquireWhisper(t *testing.T) {
+	s := &Service{whisperSlots: make(chan struct{}, 1)}
+
+	assert.True(t, s.acquireWhisper(context.Background()))
+
+	// the slot is taken, e.g. by another channel
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.False(t, s.acquireWhisper(ctx))
+
+	s.releaseWhisper()
+	assert.True(t, s.acquireWhisper(context.Background()))
+}
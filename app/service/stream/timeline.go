@@ -0,0 +1,114 @@
+package stream
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"nicemaxxingbot/app/client/whisper"
+)
+
+// timelineRetention bounds how far back toxic phrases are searched, batches are much shorter than that
+const timelineRetention = 30 * time.Minute
+
+type timedWord struct {
+	// normalized word
+	text string
+	// start of the word into the stream
+	offset time.Duration
+}
+
+// timeline keeps recently transcribed words with their stream offsets,
+// so that phrases found in accumulated text can be pinned to the moment they were said
+type timeline struct {
+	m     sync.Mutex
+	words []timedWord
+	// end of the last added chunk, words before it were already added from the previous overlapping chunk
+	until time.Duration
+}
+
+func (t *timeline) reset() {
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	t.words = nil
+	t.until = 0
+}
+
+// add appends words of a chunk that starts and ends at the given stream offsets
+func (t *timeline) add(start, end time.Duration, words []whisper.Word) {
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	for _, word := range words {
+		if start+word.End <= t.until {
+			continue
+		}
+
+		text := normalizeWords([]string{word.Text})[0]
+		if text == "" {
+			continue
+		}
+
+		t.words = append(t.words, timedWord{
+			text:   text,
+			offset: start + word.Start,
+		})
+	}
+
+	t.until = max(t.until, end)
+
+	cutoff := 0
+	for cutoff < len(t.words) && t.words[cutoff].offset < end-timelineRetention {
+		cutoff++
+	}
+	t.words = t.words[cutoff:]
+}
+
+// locate returns the stream offset of the latest occurrence of the phrase. Transcripts and LLM quotes
+// rarely match exactly, so the longest run of matching words wins if it covers at least half of the phrase.
+func (t *timeline) locate(phrase string) (time.Duration, bool) {
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	var phraseWords []string
+	for _, word := range normalizeWords(splitWords(phrase)) {
+		if word != "" {
+			phraseWords = append(phraseWords, word)
+		}
+	}
+
+	if len(phraseWords) == 0 {
+		return 0, false
+	}
+
+	bestLength := 0
+	var bestOffset time.Duration
+
+	for i := len(t.words) - 1; i >= 0; i-- {
+		for j := range phraseWords {
+			length := 0
+			for i+length < len(t.words) && j+length < len(phraseWords) && t.words[i+length].text == phraseWords[j+length] {
+				length++
+			}
+
+			if length > bestLength {
+				bestLength = length
+				bestOffset = t.words[i].offset
+			}
+		}
+	}
+
+	if bestLength == 0 || bestLength*2 < len(phraseWords) {
+		return 0, false
+	}
+
+	return bestOffset, true
+}
+
+// formatStreamOffset formats the offset as hh:mm:ss
+func formatStreamOffset(d time.Duration) string {
+	d = d.Round(time.Second)
+
+	return fmt.Sprintf("%02d:%02d:%02d", int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60)
+}
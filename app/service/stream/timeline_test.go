@@ -0,0 +1,116 @@
+package stream
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"testing"
+	"time"
+
+	"nicemaxxingbot/app/client/twitch"
+	"nicemaxxingbot/app/client/whisper"
+	"nicemaxxingbot/app/config"
+	"nicemaxxingbot/app/service/state"
+	"nicemaxxingbot/app/util/clock"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func words(start time.Duration, text ...string) []whisper.Word {
+	result := make([]whisper.Word, len(text))
+
+	for i, word := range text {
+		result[i] = whisper.Word{
+			Text:  word,
+			Start: start + time.Duration(i)*time.Second,
+			End:   start + time.Duration(i+1)*time.Second,
+		}
+	}
+
+	return result
+}
+
+func TestTimelineLocate(t *testing.T) {
+	var tl timeline
+
+	tl.add(time.Hour, time.Hour+30*time.Second, words(26*time.Second, "nurse", "players", "are", "not"))
+	// the overlapping chunk repeats the last words of the previous one
+	tl.add(time.Hour+25*time.Second, time.Hour+55*time.Second, words(2*time.Second, "players,", "are", "not", "HUMAN!", "gg"))
+
+	offset, ok := tl.locate("Nurse players are not human")
+	assert.True(t, ok)
+	assert.Equal(t, time.Hour+26*time.Second, offset)
+
+	offset, ok = tl.locate("not human")
+	assert.True(t, ok)
+	assert.Equal(t, time.Hour+29*time.Second, offset)
+
+	_, ok = tl.locate("uninstall the game right now")
+	assert.False(t, ok)
+}
+
+func TestTimelineLocateLatest(t *testing.T) {
+	var tl timeline
+
+	tl.add(0, 30*time.Second, words(0, "you", "are", "trash"))
+	tl.add(30*time.Second, time.Minute, words(0, "you", "are", "trash"))
+
+	offset, ok := tl.locate("you are trash")
+	assert.True(t, ok)
+	assert.Equal(t, 30*time.Second, offset)
+}
+
+func TestFormatStreamOffset(t *testing.T) {
+	assert.Equal(t, "01:23:45", formatStreamOffset(time.Hour+23*time.Minute+45*time.Second))
+	assert.Equal(t, "00:00:07", formatStreamOffset(6600*time.Millisecond))
+}
+
+type fakeVideoArchive map[string]string
+
+func (a fakeVideoArchive) GetArchiveVideoID(_, streamID string) (string, error) {
+	videoID, ok := a[streamID]
+	if !ok {
+		return "", fmt.Errorf("%w: stream %s", twitch.ErrNoVOD, streamID)
+	}
+
+	return videoID, nil
+}
+
+func TestLinkInVOD(t *testing.T) {
+	startedAt := time.Date(2025, 9, 1, 12, 0, 0, 0, time.UTC)
+
+	// the phrase is said 26s into the chunk that starts an hour into the stream
+	var tl timeline
+	tl.add(time.Hour, time.Hour+30*time.Second, words(26*time.Second, "nurse", "players", "are", "not", "human"))
+
+	streamOffset, located := tl.locate("Nurse players are not human")
+	require.True(t, located)
+
+	// the verdict arrives after the chunk ends, Whisper transcribes it and the batching window runs out
+	verdictAt := startedAt.Add(time.Hour + 30*time.Second + 5*time.Second + 120*time.Second)
+
+	c := &channel{
+		s: &Service{
+			cfg:        &config.Config{Twitch: config.Twitch{VODLinks: true}},
+			videos:     fakeVideoArchive{"316": "2547341592"},
+			stateStore: state.NewMemoryStore(),
+			clock:      clock.NewSimulated(verdictAt),
+		},
+		username: "k0per1s",
+		logger:   slog.Default(),
+		state:    state.ChannelState{LastToxicEvent: &state.ToxicEvent{Time: verdictAt}},
+	}
+	c.stream.Store(&twitch.StreamInfo{ID: "316", StartedAt: startedAt})
+
+	vodURL := c.linkInVOD(context.Background(), streamOffset, located)
+	assert.Equal(t, "https://www.twitch.tv/videos/2547341592?t=1h0m26s", vodURL)
+	assert.Equal(t, vodURL, c.state.LastToxicEvent.VODURL)
+
+	// the moment is unknown
+	assert.Empty(t, c.linkInVOD(context.Background(), 0, false))
+
+	// the channel has archives turned off
+	c.stream.Store(&twitch.StreamInfo{ID: "317", StartedAt: startedAt})
+	assert.Empty(t, c.linkInVOD(context.Background(), streamOffset, located))
+}
@@ -6,31 +6,37 @@ import (
 	"log/slog"
 	"nicemaxxingbot/app/client/openai"
 	"nicemaxxingbot/app/client/whisper"
+	"nicemaxxingbot/app/util/prompt"
+	"nicemaxxingbot/app/util/telemetry"
 	"time"
 
 	"github.com/avast/retry-go"
 	"github.com/samber/do"
+	"go.opentelemetry.io/otel/attribute"
+	otelmetric "go.opentelemetry.io/otel/metric"
 )
 
 type Service struct {
 	client        *openai.Client
 	whisperClient *whisper.Client
+	metrics       *telemetry.Metrics
 }
 
 func New(di *do.Injector) (*Service, error) {
 	return &Service{
 		client:        do.MustInvoke[*openai.Client](di),
 		whisperClient: do.MustInvoke[*whisper.Client](di),
+		metrics:       do.MustInvoke[*telemetry.Metrics](di),
 	}, nil
 }
 
-func (s *Service) checkToxicity(ctx context.Context, text string, useFreeClient bool) (*openai.AnalyzeResult, error) {
+func (s *Service) checkToxicity(ctx context.Context, text, prompt string, useFreeClient bool) (*openai.AnalyzeResult, error) {
 	var result *openai.AnalyzeResult
 
 	attempts := 3
 
 	err := retry.Do(func() error {
-		res, err := s.client.Analyze(ctx, text, useFreeClient)
+		res, err := s.client.AnalyzeWithPrompt(ctx, text, prompt, useFreeClient)
 		if err != nil {
 			return fmt.Errorf("CheckToxicity: %w", err)
 		}
@@ -47,22 +53,34 @@ func (s *Service) checkToxicity(ctx context.Context, text string, useFreeClient
 }
 
 func (s *Service) ProcessTranscription(ctx context.Context, text string) (*openai.AnalyzeResult, error) {
+	return s.ProcessTranscriptionWithPrompt(ctx, text, prompt.Prompt{})
+}
+
+// ProcessTranscriptionWithPrompt is ProcessTranscription with a custom system prompt, an empty prompt means the built-in one
+func (s *Service) ProcessTranscriptionWithPrompt(ctx context.Context, text string, p prompt.Prompt) (*openai.AnalyzeResult, error) {
 	slogger := slog.With(slog.String("text", text))
+	if p.Hash != "" {
+		slogger = slogger.With(slog.String("promptHash", p.Hash))
+	}
+
 	slogger.Debug("Processing text...")
 
 	start := time.Now()
 	slogger.Debug("Using free ai to check for toxicity...")
 
-	toxicResult, err := s.checkToxicity(ctx, text, true)
+	toxicResult, err := s.checkToxicity(ctx, text, p.Text, true)
 	if err != nil {
 		return nil, fmt.Errorf("checkToxicity(free): %w", err)
 	}
 
 	slogger.Debug("Checked toxicity (free)",
-		slog.Bool("result", toxicResult.Toxic),
+		slog.String("verdict", string(toxicResult.Verdict)),
+		slog.Float64("confidence", toxicResult.Confidence),
+		slog.String("rationale", toxicResult.Rationale),
 		slog.Duration("duration", time.Since(start)),
 	)
-	if !toxicResult.Toxic {
+	if !toxicResult.Toxic() {
+		s.recordVerdict(ctx, toxicResult)
 		return toxicResult, nil
 	}
 
@@ -70,15 +88,28 @@ func (s *Service) ProcessTranscription(ctx context.Context, text string) (*opena
 	start = time.Now()
 	slogger.Debug("Confirming with paid ai..")
 
-	toxicResult, err = s.checkToxicity(ctx, text, false)
+	toxicResult, err = s.checkToxicity(ctx, text, p.Text, false)
 	if err != nil {
 		return nil, fmt.Errorf("checkToxicity(paid): %w", err)
 	}
 
 	slogger.Debug("Checked toxicity (paid)",
-		slog.Bool("result", toxicResult.Toxic),
+		slog.String("verdict", string(toxicResult.Verdict)),
+		slog.Float64("confidence", toxicResult.Confidence),
+		slog.String("rationale", toxicResult.Rationale),
 		slog.Duration("duration", time.Since(start)),
 	)
 
+	s.metrics.Confirmations.Add(ctx, 1, otelmetric.WithAttributes(
+		attribute.Bool("overturned", !toxicResult.Toxic()),
+	))
+	s.recordVerdict(ctx, toxicResult)
+
 	return toxicResult, nil
 }
+
+func (s *Service) recordVerdict(ctx context.Context, result *openai.AnalyzeResult) {
+	s.metrics.Verdicts.Add(ctx, 1, otelmetric.WithAttributes(
+		attribute.String("verdict", string(result.Verdict)),
+	))
+}
@@ -0,0 +1,129 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// tone generates a sine wave of the given duration and amplitude (0..1)
+func tone(d time.Duration, amplitude float64) []byte {
+	samples := Bytes(d) / BytesPerSample
+	pcm := make([]byte, samples*BytesPerSample)
+
+	for i := 0; i < samples; i++ {
+		value := amplitude * 32767 * math.Sin(2*math.Pi*440*float64(i)/SampleRate)
+		binary.LittleEndian.PutUint16(pcm[i*BytesPerSample:], uint16(int16(value)))
+	}
+
+	return pcm
+}
+
+func concat(parts ...[]byte) []byte {
+	return bytes.Join(parts, nil)
+}
+
+func TestWAVRoundTrip(t *testing.T) {
+	pcm := tone(time.Second, 0.5)
+
+	decoded, err := DecodeWAV(EncodeWAV(pcm))
+	require.NoError(t, err)
+	require.Equal(t, pcm, decoded)
+	require.Equal(t, time.Second, Duration(decoded))
+}
+
+func TestDecodeWAVUnfinishedHeader(t *testing.T) {
+	pcm := tone(time.Second, 0.5)
+	data := EncodeWAV(pcm)
+	// interrupted writers leave the data size at zero
+	binary.LittleEndian.PutUint32(data[40:44], 0)
+
+	decoded, err := DecodeWAV(data)
+	require.NoError(t, err)
+	require.Equal(t, pcm, decoded)
+}
+
+func TestLevel(t *testing.T) {
+	require.InDelta(t, -9, Level(tone(time.Second, 0.5)), 0.1)
+	require.True(t, math.IsInf(Level(make([]byte, 100)), -1))
+}
+
+func split(t *testing.T, s Splitter, pcm []byte) []time.Duration {
+	t.Helper()
+
+	var result []time.Duration
+
+	err := s.Split(bytes.NewReader(pcm), func(chunk []byte) error {
+		result = append(result, Duration(chunk))
+		return nil
+	})
+	require.NoError(t, err)
+
+	return result
+}
+
+func TestSplitterFixed(t *testing.T) {
+	s := Splitter{MaxLength: 3 * time.Second}
+	pcm := tone(7*time.Second+500*time.Millisecond, 0.5)
+
+	require.Equal(t, []time.Duration{3 * time.Second, 3 * time.Second, 1500 * time.Millisecond}, split(t, s, pcm))
+}
+
+func TestSplitterDropsShortTail(t *testing.T) {
+	s := Splitter{MaxLength: 3 * time.Second}
+	pcm := tone(3*time.Second+300*time.Millisecond, 0.5)
+
+	require.Equal(t, []time.Duration{3 * time.Second}, split(t, s, pcm))
+}
+
+func TestSplitterSilence(t *testing.T) {
+	s := Splitter{
+		MaxLength:        10 * time.Second,
+		MinLength:        2 * time.Second,
+		SilenceThreshold: -40,
+	}
+	pcm := concat(
+		tone(time.Second, 0.5),
+		tone(300*time.Millisecond, 0), // too early to cut
+		tone(2*time.Second, 0.5),
+		tone(300*time.Millisecond, 0), // cut at the first silent frame
+		tone(12*time.Second, 0.5),     // no silence, cut at max length
+	)
+
+	chunks := split(t, s, pcm)
+	require.Len(t, chunks, 3)
+	require.InDelta(t, 3330*time.Millisecond, chunks[0], float64(FrameDuration))
+	require.Equal(t, 10*time.Second, chunks[1])
+}
+
+// hiss generates a signal at the Nyquist frequency, which crosses zero far more often than speech
+func hiss(d time.Duration, amplitude float64) []byte {
+	samples := Bytes(d) / BytesPerSample
+	pcm := make([]byte, samples*BytesPerSample)
+
+	for i := 0; i < samples; i++ {
+		value := amplitude * 32767
+		if i%2 == 1 {
+			value = -value
+		}
+		binary.LittleEndian.PutUint16(pcm[i*BytesPerSample:], uint16(int16(value)))
+	}
+
+	return pcm
+}
+
+func TestVADSpeechRatio(t *testing.T) {
+	vad := VAD{EnergyThreshold: -40}
+
+	require.InDelta(t, 1, vad.SpeechRatio(tone(3*time.Second, 0.5)), 0.01)
+	require.InDelta(t, 0, vad.SpeechRatio(tone(3*time.Second, 0)), 0.01)
+	require.InDelta(t, 0, vad.SpeechRatio(hiss(3*time.Second, 0.5)), 0.01)
+	require.InDelta(t, 0.25, vad.SpeechRatio(concat(
+		tone(time.Second+500*time.Millisecond, 0.5),
+		tone(4*time.Second+500*time.Millisecond, 0),
+	)), 0.01)
+}
@@ -0,0 +1,27 @@
+package audio
+
+import (
+	"encoding/binary"
+	"math"
+	"time"
+)
+
+// FrameDuration is the analysis window of energy based processing
+const FrameDuration = 30 * time.Millisecond
+
+// Level returns the RMS level of PCM data in dBFS, -inf for digital silence
+func Level(pcm []byte) float64 {
+	samples := len(pcm) / BytesPerSample
+	if samples == 0 {
+		return math.Inf(-1)
+	}
+
+	var sum float64
+
+	for i := 0; i < samples; i++ {
+		sample := float64(int16(binary.LittleEndian.Uint16(pcm[i*BytesPerSample:]))) / 32768
+		sum += sample * sample
+	}
+
+	return 20 * math.Log10(math.Sqrt(sum/float64(samples)))
+}
@@ -0,0 +1,62 @@
+package audio
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// minTailLength is the shortest chunk emitted at the end of the stream, shorter tails are dropped
+const minTailLength = time.Second
+
+// Splitter cuts a raw PCM stream into chunks
+type Splitter struct {
+	// MaxLength is the length of fixed-size chunks and the upper bound of silence-split ones
+	MaxLength time.Duration
+	// MinLength enables splitting on silence: once a chunk is this long, it is cut at the next silent frame
+	MinLength time.Duration
+	// SilenceThreshold is the level in dBFS below which a frame is silent
+	SilenceThreshold float64
+}
+
+// Split reads r until EOF and calls emit for every chunk
+func (s *Splitter) Split(r io.Reader, emit func(pcm []byte) error) error {
+	frameSize := Bytes(FrameDuration)
+	maxSize := Bytes(s.MaxLength)
+	minSize := Bytes(s.MinLength)
+
+	if maxSize < frameSize {
+		return fmt.Errorf("max chunk length %v is too short", s.MaxLength)
+	}
+
+	buf := make([]byte, 0, maxSize)
+	frame := make([]byte, frameSize)
+
+	for {
+		// never read past the max length, so fixed-size chunks are exact
+		n, err := io.ReadFull(r, frame[:min(frameSize, maxSize-len(buf))])
+		buf = append(buf, frame[:n]...)
+
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			if len(buf) >= Bytes(minTailLength) {
+				return emit(buf[:len(buf)-len(buf)%BytesPerSample])
+			}
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("read: %w", err)
+		}
+
+		silenceCut := minSize > 0 && len(buf) >= minSize && Level(frame[:n]) < s.SilenceThreshold
+		if !silenceCut && len(buf) < maxSize {
+			continue
+		}
+
+		if err = emit(buf); err != nil {
+			return err
+		}
+
+		buf = make([]byte, 0, maxSize)
+	}
+}
@@ -0,0 +1,69 @@
+package audio
+
+import (
+	"encoding/binary"
+)
+
+// zero-crossing rates (crossings per sample) of voiced and unvoiced speech, frames outside
+// of the range are hum, hiss or cymbals rather than a voice
+const (
+	minSpeechZCR = 0.01
+	maxSpeechZCR = 0.35
+)
+
+// VAD is an energy and zero-crossing rate based voice activity detector
+type VAD struct {
+	// EnergyThreshold is the level in dBFS below which a frame is silent
+	EnergyThreshold float64
+}
+
+// ZeroCrossingRate returns the share of adjacent samples that change sign
+func ZeroCrossingRate(pcm []byte) float64 {
+	samples := len(pcm) / BytesPerSample
+	if samples < 2 {
+		return 0
+	}
+
+	crossings := 0
+	prev := int16(binary.LittleEndian.Uint16(pcm))
+
+	for i := 1; i < samples; i++ {
+		sample := int16(binary.LittleEndian.Uint16(pcm[i*BytesPerSample:]))
+		if (prev >= 0) != (sample >= 0) {
+			crossings++
+		}
+		prev = sample
+	}
+
+	return float64(crossings) / float64(samples-1)
+}
+
+// IsSpeech reports whether a single frame looks like speech
+func (v VAD) IsSpeech(frame []byte) bool {
+	if Level(frame) < v.EnergyThreshold {
+		return false
+	}
+
+	zcr := ZeroCrossingRate(frame)
+
+	return zcr >= minSpeechZCR && zcr <= maxSpeechZCR
+}
+
+// SpeechRatio returns the share of frames of the PCM data that look like speech
+func (v VAD) SpeechRatio(pcm []byte) float64 {
+	frameSize := Bytes(FrameDuration)
+	frames, speech := 0, 0
+
+	for pos := 0; pos+frameSize <= len(pcm); pos += frameSize {
+		frames++
+		if v.IsSpeech(pcm[pos : pos+frameSize]) {
+			speech++
+		}
+	}
+
+	if frames == 0 {
+		return 0
+	}
+
+	return float64(speech) / float64(frames)
+}
@@ -0,0 +1,105 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// SampleRate of the audio the pipeline works with
+	SampleRate = 16000
+	// BytesPerSample of mono signed 16-bit little-endian PCM
+	BytesPerSample = 2
+	// BytesPerSecond of the PCM stream
+	BytesPerSecond = SampleRate * BytesPerSample
+
+	wavHeaderSize = 44
+)
+
+// Duration of the PCM data
+func Duration(pcm []byte) time.Duration {
+	return time.Duration(len(pcm)) * time.Second / BytesPerSecond
+}
+
+// Bytes is the PCM data length of the duration, aligned to whole samples
+func Bytes(d time.Duration) int {
+	return int(d*SampleRate/time.Second) * BytesPerSample
+}
+
+// EncodeWAV wraps raw PCM data into a WAV container
+func EncodeWAV(pcm []byte) []byte {
+	var buf bytes.Buffer
+	buf.Grow(wavHeaderSize + len(pcm))
+
+	buf.WriteString("RIFF")
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(36+len(pcm))) //nolint:gosec
+	buf.WriteString("WAVE")
+
+	buf.WriteString("fmt ")
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(16))
+	_ = binary.Write(&buf, binary.LittleEndian, uint16(1)) // PCM
+	_ = binary.Write(&buf, binary.LittleEndian, uint16(1)) // mono
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(SampleRate))
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(BytesPerSecond))
+	_ = binary.Write(&buf, binary.LittleEndian, uint16(BytesPerSample))
+	_ = binary.Write(&buf, binary.LittleEndian, uint16(8*BytesPerSample))
+
+	buf.WriteString("data")
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(len(pcm))) //nolint:gosec
+	buf.Write(pcm)
+
+	return buf.Bytes()
+}
+
+// DecodeWAV extracts PCM data of a 16 kHz mono 16-bit WAV file
+func DecodeWAV(data []byte) ([]byte, error) {
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WAVE" {
+		return nil, errors.New("not a WAV file")
+	}
+
+	formatChecked := false
+
+	for pos := 12; pos+8 <= len(data); {
+		id := string(data[pos : pos+4])
+		size := int(binary.LittleEndian.Uint32(data[pos+4 : pos+8]))
+		body := data[pos+8:]
+
+		switch id {
+		case "fmt ":
+			if size < 16 || len(body) < 16 {
+				return nil, errors.New("truncated WAV format chunk")
+			}
+
+			format := binary.LittleEndian.Uint16(body[0:2])
+			channels := binary.LittleEndian.Uint16(body[2:4])
+			sampleRate := binary.LittleEndian.Uint32(body[4:8])
+			bitsPerSample := binary.LittleEndian.Uint16(body[14:16])
+
+			if format != 1 || channels != 1 || sampleRate != SampleRate || bitsPerSample != 8*BytesPerSample {
+				return nil, fmt.Errorf("unsupported WAV format: format=%d channels=%d rate=%d bits=%d",
+					format, channels, sampleRate, bitsPerSample)
+			}
+
+			formatChecked = true
+
+		case "data":
+			if !formatChecked {
+				return nil, errors.New("WAV data chunk before format chunk")
+			}
+
+			// the size is not finalized if the writer was interrupted
+			if size > len(body) || size == 0 {
+				size = len(body)
+			}
+
+			return body[:size-size%BytesPerSample], nil
+		}
+
+		pos += 8 + size + size%2
+	}
+
+	return nil, errors.New("WAV data chunk not found")
+}
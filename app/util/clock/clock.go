@@ -0,0 +1,47 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock abstracts the current time, so that the pipeline can run on recorded media
+// faster than real time.
+type Clock interface {
+	Now() time.Time
+}
+
+type Real struct{}
+
+func (Real) Now() time.Time {
+	return time.Now()
+}
+
+// Simulated is a clock that only moves when told to.
+type Simulated struct {
+	m   sync.Mutex
+	now time.Time
+}
+
+func NewSimulated(start time.Time) *Simulated {
+	return &Simulated{
+		now: start,
+	}
+}
+
+func (c *Simulated) Now() time.Time {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	return c.now
+}
+
+// AdvanceTo moves the clock forward to t, it never goes backwards
+func (c *Simulated) AdvanceTo(t time.Time) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	if t.After(c.now) {
+		c.now = t
+	}
+}
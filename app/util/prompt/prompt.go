@@ -0,0 +1,106 @@
+package prompt
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+
+	"nicemaxxingbot/app/config"
+)
+
+// hashLength is the number of hex digits of the hash, enough to tell prompt versions apart
+const hashLength = 12
+
+// Vars are the variables available to prompt templates
+type Vars struct {
+	// Streamer is the channel username
+	Streamer string
+	// Game is the current Twitch category, empty if unknown
+	Game string
+	// Language is the broadcaster language (e.g. en), empty if unknown
+	Language string
+	// AllowedPhrases are phrases of the channel that must never be considered toxic
+	AllowedPhrases []string
+}
+
+// Prompt is a rendered prompt
+type Prompt struct {
+	Text string
+	// Hash identifies the exact prompt text, it is logged with verdicts
+	Hash string
+}
+
+// Template is a text/template prompt
+type Template struct {
+	name string
+	tmpl *template.Template
+}
+
+// Parse parses the prompt template, name is used in errors
+func Parse(name, text string) (*Template, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Funcs(template.FuncMap{
+		"join": strings.Join,
+	}).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("template.Parse: %w", err)
+	}
+
+	t := &Template{name: name, tmpl: tmpl}
+
+	// unknown variables only fail on execution, catch them on load instead of on the first verdict
+	if _, err = t.Render(Vars{}); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// Load parses the prompt template from the file or inline text of the config, fallback is used if both are empty.
+// It returns nil if there is no template at all.
+func Load(name string, cfg config.Prompt, fallback string) (*Template, error) {
+	text := cfg.Text
+
+	if cfg.File != "" {
+		data, err := os.ReadFile(cfg.File)
+		if err != nil {
+			return nil, fmt.Errorf("os.ReadFile: %w", err)
+		}
+
+		text = string(data)
+	}
+
+	if text == "" {
+		text = fallback
+	}
+
+	if text == "" {
+		return nil, nil
+	}
+
+	return Parse(name, text)
+}
+
+// Render executes the template with the variables
+func (t *Template) Render(vars Vars) (Prompt, error) {
+	var buf bytes.Buffer
+
+	if err := t.tmpl.Execute(&buf, vars); err != nil {
+		return Prompt{}, fmt.Errorf("failed to render prompt %s: %w", t.name, err)
+	}
+
+	return New(buf.String()), nil
+}
+
+// New wraps an already rendered prompt text
+func New(text string) Prompt {
+	sum := sha256.Sum256([]byte(text))
+
+	return Prompt{
+		Text: text,
+		Hash: hex.EncodeToString(sum[:])[:hashLength],
+	}
+}
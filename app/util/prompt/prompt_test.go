@@ -0,0 +1,73 @@
+package prompt
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"nicemaxxingbot/app/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplate_Render(t *testing.T) {
+	tmpl, err := Parse("test", `Judge {{.Streamer}} playing {{.Game}} in {{.Language}}.
+{{- range .AllowedPhrases}}
+- "{{.}}" is fine.
+{{- end}}`)
+	require.NoError(t, err)
+
+	p, err := tmpl.Render(Vars{
+		Streamer:       "k0per1s",
+		Game:           "Dead by Daylight",
+		Language:       "en",
+		AllowedPhrases: []string{"killeroid", "gg ez"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Judge k0per1s playing Dead by Daylight in en.\n- \"killeroid\" is fine.\n- \"gg ez\" is fine.", p.Text)
+	assert.Len(t, p.Hash, hashLength)
+
+	other, err := tmpl.Render(Vars{Streamer: "k0per1s"})
+	require.NoError(t, err)
+	assert.NotEqual(t, p.Hash, other.Hash)
+	assert.Equal(t, New(other.Text).Hash, other.Hash)
+}
+
+func TestParse_UnknownVariable(t *testing.T) {
+	_, err := Parse("test", "Judge {{.Channel}}")
+	assert.Error(t, err)
+
+	_, err = Parse("test", "Judge {{.Streamer")
+	assert.Error(t, err)
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prompt.txt")
+	require.NoError(t, os.WriteFile(path, []byte("from file"), 0o644))
+
+	tmpl, err := Load("test", config.Prompt{File: path, Text: "inline"}, "fallback")
+	require.NoError(t, err)
+	p, err := tmpl.Render(Vars{})
+	require.NoError(t, err)
+	assert.Equal(t, "from file", p.Text)
+
+	tmpl, err = Load("test", config.Prompt{Text: "inline"}, "fallback")
+	require.NoError(t, err)
+	p, err = tmpl.Render(Vars{})
+	require.NoError(t, err)
+	assert.Equal(t, "inline", p.Text)
+
+	tmpl, err = Load("test", config.Prompt{}, "fallback")
+	require.NoError(t, err)
+	p, err = tmpl.Render(Vars{})
+	require.NoError(t, err)
+	assert.Equal(t, "fallback", p.Text)
+
+	tmpl, err = Load("test", config.Prompt{}, "")
+	require.NoError(t, err)
+	assert.Nil(t, tmpl)
+
+	_, err = Load("test", config.Prompt{File: filepath.Join(t.TempDir(), "missing.txt")}, "")
+	assert.Error(t, err)
+}
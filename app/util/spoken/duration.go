@@ -0,0 +1,114 @@
+package spoken
+
+import (
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var numbers = map[string]float64{
+	"a": 1, "an": 1, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7, "eight": 8,
+	"nine": 9, "ten": 10, "twelve": 12, "fifteen": 15, "twenty": 20, "thirty": 30, "forty": 40, "fifty": 50,
+	"couple": 2, "few": 3,
+	"один": 1, "одну": 1, "два": 2, "две": 2, "три": 3, "четыре": 4, "пять": 5, "шесть": 6, "семь": 7,
+	"восемь": 8, "девять": 9, "десять": 10, "двенадцать": 12, "пятнадцать": 15, "двадцать": 20, "тридцать": 30,
+	"сорок": 40, "пятьдесят": 50, "пару": 2, "несколько": 3, "полтора": 1.5, "полторы": 1.5,
+}
+
+// units are exact word forms, prefixes would also match ordinary words like "часто" or "minimal"
+var units = map[string]time.Duration{
+	"minute": time.Minute, "minutes": time.Minute, "min": time.Minute, "mins": time.Minute,
+	"hour": time.Hour, "hours": time.Hour, "hr": time.Hour, "hrs": time.Hour,
+	"day": 24 * time.Hour, "days": 24 * time.Hour,
+	"week": 7 * 24 * time.Hour, "weeks": 7 * 24 * time.Hour,
+	"минута": time.Minute, "минуту": time.Minute, "минуты": time.Minute, "минут": time.Minute, "мин": time.Minute,
+	"час": time.Hour, "часа": time.Hour, "часов": time.Hour,
+	"день": 24 * time.Hour, "дня": 24 * time.Hour, "дней": 24 * time.Hour, "сутки": 24 * time.Hour, "суток": 24 * time.Hour,
+	"неделя": 7 * 24 * time.Hour, "неделю": 7 * 24 * time.Hour, "недели": 7 * 24 * time.Hour, "недель": 7 * 24 * time.Hour,
+}
+
+// ParseDuration finds a duration in a spoken or typed request, e.g. "mute for 2 hours",
+// "an hour and a half", "30m" or "на два часа". Several durations are summed up ("1 hour 30 minutes").
+// It returns false if the text has no duration.
+func ParseDuration(text string) (time.Duration, bool) {
+	tokens := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '.'
+	})
+
+	var total time.Duration
+
+	for i := 0; i < len(tokens); i++ {
+		token := strings.Trim(tokens[i], ".")
+
+		// typed durations like 2h or 1h30m
+		if d, err := time.ParseDuration(token); err == nil && d > 0 {
+			total += d
+			continue
+		}
+
+		switch token {
+		case "полчаса":
+			total += 30 * time.Minute
+			continue
+		case "half":
+			// half an hour
+			if i+2 < len(tokens) && (tokens[i+1] == "a" || tokens[i+1] == "an") {
+				if unit, ok := parseUnit(tokens[i+2]); ok {
+					total += unit / 2
+					i += 2
+				}
+			}
+			continue
+		}
+
+		count, ok := parseNumber(token)
+		if !ok {
+			// "на час" is one hour
+			if token == "час" {
+				total += time.Hour
+			}
+			continue
+		}
+
+		next := i + 1
+		if next >= len(tokens) {
+			continue
+		}
+
+		unit, ok := parseUnit(tokens[next])
+		if !ok {
+			continue
+		}
+
+		total += time.Duration(count * float64(unit))
+		i = next
+
+		// an hour and a half
+		if i+3 < len(tokens) && tokens[i+1] == "and" && tokens[i+2] == "a" && tokens[i+3] == "half" {
+			total += unit / 2
+			i += 3
+		}
+	}
+
+	return total, total > 0
+}
+
+func parseNumber(token string) (float64, bool) {
+	if n, ok := numbers[token]; ok {
+		return n, true
+	}
+
+	n, err := strconv.ParseFloat(token, 64)
+	if err != nil || n <= 0 {
+		return 0, false
+	}
+
+	return n, true
+}
+
+func parseUnit(token string) (time.Duration, bool) {
+	unit, ok := units[token]
+
+	return unit, ok
+}
@@ -0,0 +1,45 @@
+package spoken
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected time.Duration
+		ok       bool
+	}{
+		{text: "please disable the bot for 2 hours", expected: 2 * time.Hour, ok: true},
+		{text: "mute the bot for an hour and a half", expected: 90 * time.Minute, ok: true},
+		{text: "turn off the bot for half an hour", expected: 30 * time.Minute, ok: true},
+		{text: "bot off for 1 hour 30 minutes", expected: 90 * time.Minute, ok: true},
+		{text: "disable the bot for fifteen minutes", expected: 15 * time.Minute, ok: true},
+		{text: "2h30m", expected: 150 * time.Minute, ok: true},
+		{text: "1.5 hours", expected: 90 * time.Minute, ok: true},
+		{text: "three days", expected: 72 * time.Hour, ok: true},
+		{text: "выключи бота на два часа", expected: 2 * time.Hour, ok: true},
+		{text: "выключи бота на час", expected: time.Hour, ok: true},
+		{text: "выключи бота на полчаса", expected: 30 * time.Minute, ok: true},
+		{text: "выключи бота на 15 минут", expected: 15 * time.Minute, ok: true},
+		{text: "выключи бота на полторы недели", expected: 252 * time.Hour, ok: true},
+		{text: "please disable the bot", ok: false},
+		// words that only start like units
+		{text: "бот часто ошибается", ok: false},
+		{text: "a daylight match", ok: false},
+		{text: "one minimal mistake", ok: false},
+		{text: "два минуса", ok: false},
+		{text: "", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			d, ok := ParseDuration(tt.text)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.expected, d)
+		})
+	}
+}
@@ -3,11 +3,149 @@ package telemetry
 import (
 	"nicemaxxingbot/app/config"
 
+	"github.com/samber/oops"
 	otelmetric "go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
 )
 
-type Metrics struct{}
+type Metrics struct {
+	// ChunksProduced counts audio chunks cut from the stream
+	ChunksProduced otelmetric.Int64Counter
+	// ChunksTranscribed counts chunks successfully transcribed by Whisper
+	ChunksTranscribed otelmetric.Int64Counter
+	// ChunksSkipped counts chunks not sent to Whisper, split by the reason attribute
+	// (vad: no speech, category: the stream category is ignored)
+	ChunksSkipped otelmetric.Int64Counter
+	// ChunksDropped counts chunks discarded because the transcription queue was full
+	ChunksDropped otelmetric.Int64Counter
+	// QueueDepth is the number of chunks of a channel waiting for a Whisper worker
+	QueueDepth otelmetric.Int64Gauge
+	// ChunksFailed counts chunks that failed to be transcribed
+	ChunksFailed otelmetric.Int64Counter
+	// WhisperLatency is the duration of Whisper transcription requests
+	WhisperLatency otelmetric.Float64Histogram
+	// LLMLatency is the duration of LLM requests, split by the model attribute (free/paid)
+	LLMLatency otelmetric.Float64Histogram
+	// Verdicts counts final LLM verdicts by type
+	Verdicts otelmetric.Int64Counter
+	// Confirmations counts paid model confirmations of free model TOXIC verdicts, split by the overturned attribute
+	Confirmations otelmetric.Int64Counter
+	// ChatMessages counts messages sent to Twitch chat
+	ChatMessages otelmetric.Int64Counter
+	// FFmpegRestarts counts ffmpeg restarts after the first start of a channel pipeline
+	FFmpegRestarts otelmetric.Int64Counter
+	// HLSSegments counts media segments of the native HLS ingest, split by the status attribute (ok/failed/missed)
+	HLSSegments otelmetric.Int64Counter
+	// AdSeconds counts seconds of Twitch ad breaks dropped before transcription
+	AdSeconds otelmetric.Float64Counter
+	// StreakLength is the current nicemaxxing streak length of a channel
+	StreakLength otelmetric.Float64Gauge
+}
+
+func NewMetrics(_ *config.Config, meter otelmetric.Meter) (*Metrics, error) {
+	var m Metrics
+	var err error
+
+	if m.ChunksProduced, err = meter.Int64Counter("chunks.produced",
+		otelmetric.WithDescription("Audio chunks cut from the stream"),
+	); err != nil {
+		return nil, oops.Errorf("failed to create chunks.produced counter: %w", err)
+	}
+
+	if m.ChunksTranscribed, err = meter.Int64Counter("chunks.transcribed",
+		otelmetric.WithDescription("Audio chunks transcribed by Whisper"),
+	); err != nil {
+		return nil, oops.Errorf("failed to create chunks.transcribed counter: %w", err)
+	}
+
+	if m.ChunksSkipped, err = meter.Int64Counter("chunks.skipped",
+		otelmetric.WithDescription("Audio chunks not sent to Whisper, by reason (vad/category)"),
+	); err != nil {
+		return nil, oops.Errorf("failed to create chunks.skipped counter: %w", err)
+	}
+
+	if m.ChunksDropped, err = meter.Int64Counter("chunks.dropped",
+		otelmetric.WithDescription("Audio chunks dropped because the transcription queue was full"),
+	); err != nil {
+		return nil, oops.Errorf("failed to create chunks.dropped counter: %w", err)
+	}
+
+	if m.QueueDepth, err = meter.Int64Gauge("chunks.queue_depth",
+		otelmetric.WithDescription("Audio chunks waiting for a Whisper worker"),
+	); err != nil {
+		return nil, oops.Errorf("failed to create chunks.queue_depth gauge: %w", err)
+	}
+
+	if m.ChunksFailed, err = meter.Int64Counter("chunks.failed",
+		otelmetric.WithDescription("Audio chunks that failed to be transcribed"),
+	); err != nil {
+		return nil, oops.Errorf("failed to create chunks.failed counter: %w", err)
+	}
+
+	if m.WhisperLatency, err = meter.Float64Histogram("whisper.latency",
+		otelmetric.WithDescription("Whisper transcription request duration"),
+		otelmetric.WithUnit("s"),
+	); err != nil {
+		return nil, oops.Errorf("failed to create whisper.latency histogram: %w", err)
+	}
+
+	if m.LLMLatency, err = meter.Float64Histogram("llm.latency",
+		otelmetric.WithDescription("LLM request duration"),
+		otelmetric.WithUnit("s"),
+	); err != nil {
+		return nil, oops.Errorf("failed to create llm.latency histogram: %w", err)
+	}
+
+	if m.Verdicts, err = meter.Int64Counter("llm.verdicts",
+		otelmetric.WithDescription("Final LLM verdicts by type"),
+	); err != nil {
+		return nil, oops.Errorf("failed to create llm.verdicts counter: %w", err)
+	}
+
+	if m.Confirmations, err = meter.Int64Counter("llm.confirmations",
+		otelmetric.WithDescription("Paid model confirmations of TOXIC verdicts, overturned or not"),
+	); err != nil {
+		return nil, oops.Errorf("failed to create llm.confirmations counter: %w", err)
+	}
+
+	if m.ChatMessages, err = meter.Int64Counter("chat.messages",
+		otelmetric.WithDescription("Messages sent to Twitch chat"),
+	); err != nil {
+		return nil, oops.Errorf("failed to create chat.messages counter: %w", err)
+	}
+
+	if m.FFmpegRestarts, err = meter.Int64Counter("ffmpeg.restarts",
+		otelmetric.WithDescription("FFmpeg restarts of channel pipelines"),
+	); err != nil {
+		return nil, oops.Errorf("failed to create ffmpeg.restarts counter: %w", err)
+	}
+
+	if m.HLSSegments, err = meter.Int64Counter("hls.segments",
+		otelmetric.WithDescription("HLS media segments by download status"),
+	); err != nil {
+		return nil, oops.Errorf("failed to create hls.segments counter: %w", err)
+	}
+
+	if m.AdSeconds, err = meter.Float64Counter("ads.skipped",
+		otelmetric.WithDescription("Seconds of ad breaks dropped before transcription"),
+		otelmetric.WithUnit("s"),
+	); err != nil {
+		return nil, oops.Errorf("failed to create ads.skipped counter: %w", err)
+	}
+
+	if m.StreakLength, err = meter.Float64Gauge("streak.length",
+		otelmetric.WithDescription("Current nicemaxxing streak length"),
+		otelmetric.WithUnit("s"),
+	); err != nil {
+		return nil, oops.Errorf("failed to create streak.length gauge: %w", err)
+	}
+
+	return &m, nil
+}
+
+// NewNoopMetrics creates metrics that are not recorded anywhere, for commands that don't export telemetry
+func NewNoopMetrics() *Metrics {
+	metrics, _ := NewMetrics(nil, noop.NewMeterProvider().Meter(""))
 
-func NewMetrics(_ *config.Config, _ otelmetric.Meter) (*Metrics, error) {
-	return &Metrics{}, nil
+	return metrics
 }
@@ -3,16 +3,20 @@ package telemetry
 import (
 	"context"
 	"log/slog"
+	"net/http"
 	"nicemaxxingbot/app/config"
 	"time"
 
 	"github.com/getsentry/sentry-go"
 	sentryotel "github.com/getsentry/sentry-go/otel"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/samber/oops"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploghttp"
 	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	otelprometheus "go.opentelemetry.io/otel/exporters/prometheus"
 	log2 "go.opentelemetry.io/otel/log"
 	otelmetric "go.opentelemetry.io/otel/metric"
 	"go.opentelemetry.io/otel/propagation"
@@ -33,13 +37,30 @@ type Telemetry struct {
 	Tracer         oteltrace.Tracer
 	Meter          otelmetric.Meter
 	Logger         log2.Logger
+	// MetricsHandler serves metrics of the MeterProvider in prometheus format, nil unless the server is enabled
+	MetricsHandler http.Handler
 	Shutdown       func(context.Context) error
 }
 
 func Init(cfg *config.Config) (*Telemetry, error) {
+	var meterOptions []sdkmetric.Option
+	var metricsHandler http.Handler
+
+	if cfg.Server.Enabled {
+		registry := prometheus.NewRegistry()
+
+		exporter, err := otelprometheus.New(otelprometheus.WithRegisterer(registry))
+		if err != nil {
+			return nil, oops.Errorf("failed to create prometheus exporter: %w", err)
+		}
+
+		meterOptions = append(meterOptions, sdkmetric.WithReader(exporter))
+		metricsHandler = promhttp.HandlerFor(registry, promhttp.HandlerOpts{}) //nolint:exhaustruct
+	}
+
 	if !cfg.Telemetry.Enabled {
 		noopTracerProvider := noop.NewTracerProvider()
-		noopMeterProvider := sdkmetric.NewMeterProvider()
+		noopMeterProvider := sdkmetric.NewMeterProvider(meterOptions...)
 		noopLoggerProvider := log.NewLoggerProvider()
 
 		return &Telemetry{
@@ -49,7 +70,8 @@ func Init(cfg *config.Config) (*Telemetry, error) {
 			Tracer:         noopTracerProvider.Tracer(cfg.ServiceName),
 			Meter:          noopMeterProvider.Meter(cfg.ServiceName),
 			Logger:         noopLoggerProvider.Logger(cfg.ServiceName),
-			Shutdown:       func(context.Context) error { return nil },
+			MetricsHandler: metricsHandler,
+			Shutdown:       noopMeterProvider.Shutdown,
 		}, nil
 	}
 
@@ -74,7 +96,7 @@ func Init(cfg *config.Config) (*Telemetry, error) {
 	}
 	shutdownFuncs = append(shutdownFuncs, tracerProvider.Shutdown)
 
-	meterProvider, err := initMeterProvider(ctx, res)
+	meterProvider, err := initMeterProvider(ctx, res, meterOptions...)
 	if err != nil {
 		return nil, oops.Errorf("failed to initialize meter provider: %w", err)
 	}
@@ -118,6 +140,7 @@ func Init(cfg *config.Config) (*Telemetry, error) {
 		Tracer:         tracer,
 		Meter:          meter,
 		Logger:         logger,
+		MetricsHandler: metricsHandler,
 		Shutdown:       shutdown,
 	}, nil
 }
@@ -136,18 +159,20 @@ func initTracerProvider(ctx context.Context, res *resource.Resource) (*sdktrace.
 	), nil
 }
 
-func initMeterProvider(ctx context.Context, res *resource.Resource) (*sdkmetric.MeterProvider, error) {
+func initMeterProvider(ctx context.Context, res *resource.Resource, extraOptions ...sdkmetric.Option) (*sdkmetric.MeterProvider, error) {
 	exporter, err := otlpmetrichttp.New(ctx)
 	if err != nil {
 		return nil, oops.Errorf("failed to create OTLP meter exporter: %w", err)
 	}
 
-	return sdkmetric.NewMeterProvider(
+	options := append([]sdkmetric.Option{
 		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter,
 			sdkmetric.WithInterval(time.Minute),
 		)),
 		sdkmetric.WithResource(res),
-	), nil
+	}, extraOptions...)
+
+	return sdkmetric.NewMeterProvider(options...), nil
 }
 
 func initLogProvider(ctx context.Context, res *resource.Resource) (*log.LoggerProvider, error) {
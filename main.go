@@ -7,6 +7,8 @@ import (
 	"nicemaxxingbot/app/util"
 	"nicemaxxingbot/app/util/mylog"
 	"os"
+	// quiet hours use IANA timezones, the runtime image has no tzdata
+	_ "time/tzdata"
 
 	"github.com/spf13/cobra"
 	"go.szostok.io/version/extension"
@@ -19,6 +21,9 @@ func main() {
 
 	rootCmd := &cobra.Command{Use: "nicemaxxingbot"}
 	rootCmd.AddCommand(cmd.Run)
+	rootCmd.AddCommand(cmd.Eval)
+	rootCmd.AddCommand(cmd.Replay)
+	rootCmd.AddCommand(cmd.History)
 	rootCmd.AddCommand(extension.NewVersionCobraCmd())
 
 	if err := rootCmd.Execute(); err != nil {